@@ -0,0 +1,87 @@
+// Runs a scene on this machine and streams it over a WebSocket, with a viewer at
+// http://localhost:8080 that can also spawn bodies, hit them and pause.
+//
+//	go run ./cmd/p2dserve scenes/stacking.json
+//
+// Anyone who can reach the address can change the world, so think twice before
+// listening on anything other than localhost. Over SSH, forward the port instead
+// (ssh -L 8080:localhost:8080 simbox).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+	"github.com/lwbuchanan/Physics2D/physics2d/server"
+)
+
+type options struct {
+	scenePath string
+	addr      string
+	dt        float64
+	fps       float64
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.addr, "addr", "localhost:8080", "address to listen on")
+	flag.Float64Var(&opts.dt, "dt", 1.0/120, "seconds per physics step")
+	flag.Float64Var(&opts.fps, "fps", 30, "states sent per second")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: p2dserve [flags] scene.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || opts.dt <= 0 || opts.fps <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.scenePath = flag.Arg(0)
+
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "p2dserve:", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts options) error {
+	world, err := p2d.LoadScene(opts.scenePath)
+	if err != nil {
+		return err
+	}
+	srv, err := server.NewServer(&world, opts.dt)
+	if err != nil {
+		return err
+	}
+	srv.FrameRate = opts.fps
+
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "p2dserve: watching %s at http://%s\n", opts.scenePath, listener.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{Handler: srv.Handler()}
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+	go srv.Run(ctx)
+
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Runs a scene file without a window and writes out what every body did, so
+// scripts and CI can use the engine without raylib or a display.
+//
+//	go run ./cmd/p2dsim -steps 600 -format jsonl scenes/stacking.json > out.jsonl
+//
+// It can also draw the run, as numbered PNG frames or an animated GIF, or the
+// last step as an SVG with the paths every body took:
+//
+//	go run ./cmd/p2dsim -steps 600 -every 4 -gif run.gif -svg end.svg -o /dev/null scenes/stacking.json
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+	"github.com/lwbuchanan/Physics2D/physics2d/raster"
+	"github.com/lwbuchanan/Physics2D/physics2d/svg"
+)
+
+type options struct {
+	scenePath  string
+	outputPath string
+	format     string
+	steps      int
+	dt         float64
+	every      int
+
+	pngDir         string
+	gifPath        string
+	svgPath        string
+	pixelsPerMeter float64
+}
+
+// One body on one step
+type sample struct {
+	Step               int     `json:"step"`
+	Time               float64 `json:"time"`
+	ID                 uint64  `json:"id"`
+	X                  float64 `json:"x"`
+	Y                  float64 `json:"y"`
+	VX                 float64 `json:"vx"`
+	VY                 float64 `json:"vy"`
+	Rotation           float64 `json:"rotation"`
+	RotationalVelocity float64 `json:"rotationalVelocity"`
+	KineticEnergy      float64 `json:"kineticEnergy"`
+	PotentialEnergy    float64 `json:"potentialEnergy"`
+	Energy             float64 `json:"energy"`
+}
+
+var csvHeader = []string{
+	"step", "time", "id", "x", "y", "vx", "vy", "rotation", "rotationalVelocity",
+	"kineticEnergy", "potentialEnergy", "energy",
+}
+
+func (s sample) csvRecord() []string {
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return []string{
+		strconv.Itoa(s.Step), f(s.Time), strconv.FormatUint(s.ID, 10),
+		f(s.X), f(s.Y), f(s.VX), f(s.VY), f(s.Rotation), f(s.RotationalVelocity),
+		f(s.KineticEnergy), f(s.PotentialEnergy), f(s.Energy),
+	}
+}
+
+func newSample(step int, time float64, gravity float64, b *p2d.Body) sample {
+	position := b.Position()
+	velocity := b.Velocity()
+	kinetic := 0.5*b.Mass()*velocity.LengthSquared() +
+		0.5*b.MomentOfIntertia()*b.RotationalVelocity()*b.RotationalVelocity()
+	// Measured from y = 0
+	potential := b.Mass() * gravity * position.Y()
+	return sample{
+		Step:               step,
+		Time:               time,
+		ID:                 b.ID(),
+		X:                  position.X(),
+		Y:                  position.Y(),
+		VX:                 velocity.X(),
+		VY:                 velocity.Y(),
+		Rotation:           b.Rotation(),
+		RotationalVelocity: b.RotationalVelocity(),
+		KineticEnergy:      kinetic,
+		PotentialEnergy:    potential,
+		Energy:             kinetic + potential,
+	}
+}
+
+func main() {
+	var opts options
+	flag.IntVar(&opts.steps, "steps", 600, "number of steps to run")
+	flag.Float64Var(&opts.dt, "dt", 1.0/120, "seconds per step")
+	flag.StringVar(&opts.format, "format", "csv", "output format, csv or jsonl")
+	flag.StringVar(&opts.outputPath, "o", "", "file to write to (default stdout)")
+	flag.IntVar(&opts.every, "every", 1, "only write (and draw) every nth step")
+	flag.StringVar(&opts.pngDir, "png", "", "directory to draw PNG frames into")
+	flag.StringVar(&opts.gifPath, "gif", "", "file to draw an animated GIF into")
+	flag.StringVar(&opts.svgPath, "svg", "", "file to draw the last step and every body's path into")
+	flag.Float64Var(&opts.pixelsPerMeter, "scale", 200, "pixels per meter when drawing")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: p2dsim [flags] scene.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || opts.steps < 0 || opts.dt <= 0 || opts.every < 1 || opts.pixelsPerMeter <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if opts.format != "csv" && opts.format != "jsonl" {
+		fmt.Fprintf(os.Stderr, "p2dsim: unknown format %q\n", opts.format)
+		os.Exit(2)
+	}
+	opts.scenePath = flag.Arg(0)
+
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "p2dsim:", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts options) error {
+	world, err := p2d.LoadScene(opts.scenePath)
+	if err != nil {
+		return err
+	}
+	world.Deterministic = true
+
+	var out io.Writer = os.Stdout
+	if opts.outputPath != "" {
+		file, err := os.Create(opts.outputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+
+	var write func(s sample) error
+	var csvWriter *csv.Writer
+	if opts.format == "csv" {
+		csvWriter = csv.NewWriter(buffered)
+		if err := csvWriter.Write(csvHeader); err != nil {
+			return err
+		}
+		write = func(s sample) error {
+			return csvWriter.Write(s.csvRecord())
+		}
+	} else {
+		encoder := json.NewEncoder(buffered)
+		write = func(s sample) error {
+			return encoder.Encode(s)
+		}
+	}
+
+	renderer := raster.NewRenderer(&world, opts.pixelsPerMeter)
+	var frame *image.RGBA
+	var pngs *raster.PNGSequence
+	if opts.pngDir != "" {
+		if pngs, err = raster.NewPNGSequence(opts.pngDir); err != nil {
+			return err
+		}
+	}
+	var anim *raster.GIF
+	if opts.gifPath != "" {
+		// GIF delays are in 100ths of a second, and most viewers ignore anything under 2
+		delay := int(math.Round(float64(opts.every) * opts.dt * 100))
+		anim = raster.NewGIF(max(delay, 2))
+	}
+
+	var tracer *svg.Tracer
+	if opts.svgPath != "" {
+		tracer = svg.NewTracer()
+	}
+
+	// Step 0 is the scene as it was loaded
+	for step := 0; step <= opts.steps; step++ {
+		if step > 0 {
+			world.UpdatePhysics(opts.dt)
+		}
+		if step%opts.every != 0 {
+			continue
+		}
+		for _, b := range world.Bodies {
+			if err := write(newSample(step, float64(step)*opts.dt, world.Gravity(), b)); err != nil {
+				return err
+			}
+		}
+
+		if tracer != nil {
+			tracer.Record(&world)
+		}
+		if pngs != nil || anim != nil {
+			if frame == nil {
+				frame = renderer.Render(&world)
+			} else {
+				renderer.RenderInto(frame, &world)
+			}
+			if pngs != nil {
+				if err := pngs.AddFrame(frame); err != nil {
+					return err
+				}
+			}
+			if anim != nil {
+				anim.AddFrame(frame)
+			}
+		}
+	}
+
+	if tracer != nil {
+		svgOpts := svg.DefaultOptions()
+		svgOpts.PixelsPerMeter = opts.pixelsPerMeter
+		svgOpts.Traces = tracer
+		if err := svg.WriteFile(opts.svgPath, &world, svgOpts); err != nil {
+			return err
+		}
+	}
+	if anim != nil {
+		if err := anim.WriteFile(opts.gifPath); err != nil {
+			return err
+		}
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+)
+
+// Braille characters have 2x4 dots each, which gives us 8 times as many "pixels" as
+// the terminal has characters. Every character can only have one color, so the last
+// thing drawn into it wins.
+type canvas struct {
+	cols, rows     int // Characters
+	dots           []bool
+	colors         []color.RGBA // One per character
+	pixelsPerMeter float64      // Dots per meter
+	worldHeight    float64
+}
+
+func newCanvas(cols, rows int, world *p2d.World) *canvas {
+	dimensions := world.Dimensions()
+	width := float64(cols * 2)
+	height := float64(rows * 4)
+	return &canvas{
+		cols:           cols,
+		rows:           rows,
+		dots:           make([]bool, cols*2*rows*4),
+		colors:         make([]color.RGBA, cols*rows),
+		pixelsPerMeter: math.Min(width/dimensions.X(), height/dimensions.Y()),
+		worldHeight:    dimensions.Y(),
+	}
+}
+
+func (c *canvas) clear() {
+	clear(c.dots)
+	clear(c.colors)
+}
+
+// Same as toRLVec in the demo, but in dots
+func (c *canvas) toDot(v p2d.Vec2) (float64, float64) {
+	return v.X() * c.pixelsPerMeter, (c.worldHeight - v.Y()) * c.pixelsPerMeter
+}
+
+// Terminals can't blend, so translucent colors are drawn at full strength
+func (c *canvas) set(x, y int, col color.RGBA) {
+	if x < 0 || y < 0 || x >= c.cols*2 || y >= c.rows*4 {
+		return
+	}
+	c.dots[y*c.cols*2+x] = true
+	straight := color.NRGBAModel.Convert(col).(color.NRGBA)
+	c.colors[(y/4)*c.cols+x/2] = color.RGBA{straight.R, straight.G, straight.B, 255}
+}
+
+// Calls f for every dot in the box, clipped to the canvas
+func (c *canvas) eachDot(minX, minY, maxX, maxY float64, f func(x, y int)) {
+	x0 := max(int(math.Floor(minX)), 0)
+	y0 := max(int(math.Floor(minY)), 0)
+	x1 := min(int(math.Ceil(maxX)), c.cols*2-1)
+	y1 := min(int(math.Ceil(maxY)), c.rows*4-1)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			f(x, y)
+		}
+	}
+}
+
+// The rest implements physics2d.DebugDraw
+
+func (c *canvas) DrawPolygon(vertices []p2d.Vec2, col color.RGBA) {
+	if len(vertices) < 3 {
+		return
+	}
+	points := make([][2]float64, len(vertices))
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i, v := range vertices {
+		x, y := c.toDot(v)
+		points[i] = [2]float64{x, y}
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	c.eachDot(minX, minY, maxX, maxY, func(x, y int) {
+		if insideConvex(points, float64(x)+0.5, float64(y)+0.5) {
+			c.set(x, y, col)
+		}
+	})
+	// Thin polygons can fall between the dots, so always draw the outline too
+	for i := range points {
+		c.line(points[i], points[(i+1)%len(points)], col)
+	}
+}
+
+func (c *canvas) DrawCircle(center p2d.Vec2, radius float64, col color.RGBA) {
+	cx, cy := c.toDot(center)
+	r := math.Max(radius*c.pixelsPerMeter, 0.5)
+	c.eachDot(cx-r, cy-r, cx+r, cy+r, func(x, y int) {
+		dx := float64(x) + 0.5 - cx
+		dy := float64(y) + 0.5 - cy
+		if dx*dx+dy*dy <= r*r {
+			c.set(x, y, col)
+		}
+	})
+}
+
+func (c *canvas) DrawSegment(a, b p2d.Vec2, col color.RGBA) {
+	x0, y0 := c.toDot(a)
+	x1, y1 := c.toDot(b)
+	c.line([2]float64{x0, y0}, [2]float64{x1, y1}, col)
+}
+
+// Dots are already about as big as anything we'd draw, so every point is one dot
+func (c *canvas) DrawPoint(p p2d.Vec2, size float64, col color.RGBA) {
+	x, y := c.toDot(p)
+	c.set(int(x), int(y), col)
+}
+
+func (c *canvas) DrawTransform(xf p2d.Transform) {
+	const axisLength = 0.1 // m
+	c.DrawSegment(xf.Pos, xf.Pos.Add(xf.Rotate(p2d.NewVec2(axisLength, 0))), color.RGBA{230, 41, 55, 255})
+	c.DrawSegment(xf.Pos, xf.Pos.Add(xf.Rotate(p2d.NewVec2(0, axisLength))), color.RGBA{0, 228, 48, 255})
+}
+
+func (c *canvas) line(a, b [2]float64, col color.RGBA) {
+	steps := int(math.Ceil(math.Max(math.Abs(b[0]-a[0]), math.Abs(b[1]-a[1]))))
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		c.set(int(a[0]+(b[0]-a[0])*t), int(a[1]+(b[1]-a[1])*t), col)
+	}
+}
+
+// Inside if the point is on the same side of every edge
+func insideConvex(points [][2]float64, x, y float64) bool {
+	sign := 0.0
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		cross := (q[0]-p[0])*(y-p[1]) - (q[1]-p[1])*(x-p[0])
+		if cross*sign < 0 {
+			return false
+		}
+		if cross != 0 {
+			sign = cross
+		}
+	}
+	return true
+}
+
+// Bit for each dot in a braille character, by [y][x]
+var brailleBits = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Writes the whole canvas with 24 bit ANSI colors, only changing color when it has to
+func (c *canvas) render(sb *strings.Builder) {
+	var current color.RGBA
+	for row := range c.rows {
+		for col := range c.cols {
+			char := rune(0x2800)
+			for dy := range 4 {
+				for dx := range 2 {
+					if c.dots[(row*4+dy)*c.cols*2+col*2+dx] {
+						char |= brailleBits[dy][dx]
+					}
+				}
+			}
+			if char == 0x2800 {
+				sb.WriteByte(' ')
+				continue
+			}
+			if cellColor := c.colors[row*c.cols+col]; cellColor != current {
+				current = cellColor
+				fmt.Fprintf(sb, "\x1b[38;2;%d;%d;%dm", current.R, current.G, current.B)
+			}
+			sb.WriteRune(char)
+		}
+		// The terminal is in raw mode, so newlines don't go back to the start of the line
+		sb.WriteString("\r\n")
+	}
+	sb.WriteString("\x1b[0m")
+}
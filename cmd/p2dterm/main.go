@@ -0,0 +1,221 @@
+// Plays a scene file in the terminal, drawn with braille characters and ANSI
+// colors, for when there's no display for the raylib demo (over SSH, say).
+//
+//	go run ./cmd/p2dterm -fps 30 scenes/stacking.json
+//
+// Keys: space pauses, n steps once while paused, d cycles what's drawn, r reloads
+// the scene and q quits. The terminal needs 24 bit color and stty.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+)
+
+type options struct {
+	scenePath  string
+	fps        float64
+	dt         float64
+	cols, rows int // 0 fits the terminal
+}
+
+func main() {
+	var opts options
+	flag.Float64Var(&opts.fps, "fps", 30, "frames drawn per second")
+	flag.Float64Var(&opts.dt, "dt", 1.0/120, "seconds per physics step")
+	flag.IntVar(&opts.cols, "cols", 0, "width in characters (default fits the terminal)")
+	flag.IntVar(&opts.rows, "rows", 0, "height in characters (default fits the terminal)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: p2dterm [flags] scene.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || opts.fps <= 0 || opts.dt <= 0 || opts.cols < 0 || opts.rows < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.scenePath = flag.Arg(0)
+
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "p2dterm:", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts options) error {
+	world, err := p2d.LoadScene(opts.scenePath)
+	if err != nil {
+		return err
+	}
+	stepper, err := p2d.NewStepper(&world, opts.dt)
+	if err != nil {
+		return err
+	}
+
+	cols, rows := opts.cols, opts.rows
+	if cols == 0 || rows == 0 {
+		termRows, termCols, err := terminalSize()
+		if err != nil {
+			return err
+		}
+		if cols == 0 {
+			cols = termCols
+		}
+		if rows == 0 {
+			// Leave a line for the status bar
+			rows = termRows - 1
+		}
+	}
+	if cols < 1 || rows < 1 {
+		return fmt.Errorf("terminal is too small (%dx%d)", cols, rows)
+	}
+	c := newCanvas(cols, rows, &world)
+
+	restore, err := rawMode()
+	if err != nil {
+		return err
+	}
+	// Alternate screen, hide the cursor and clear
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l\x1b[2J")
+	defer func() {
+		os.Stdout.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	keys := make(chan byte)
+	go readKeys(keys)
+	// Ctrl-C comes in as a key in raw mode, but something else could still kill us
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+
+	flags := p2d.DrawShapes
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.fps))
+	defer ticker.Stop()
+	last := time.Now()
+	var sb strings.Builder
+
+	for {
+		select {
+		case <-signals:
+			return nil
+		case key, ok := <-keys:
+			if !ok {
+				// Stdin is gone, so just keep drawing
+				keys = nil
+				continue
+			}
+			switch key {
+			case 'q', 3: // Ctrl-C
+				return nil
+			case ' ':
+				world.Paused = !world.Paused
+			case 'n', '.':
+				if world.Paused {
+					world.Paused = false
+					world.UpdatePhysics(opts.dt)
+					world.Paused = true
+				}
+			case 'd':
+				switch flags {
+				case p2d.DrawShapes:
+					flags = p2d.DrawShapes | p2d.DrawContactPoints | p2d.DrawContactNormals
+				case p2d.DrawShapes | p2d.DrawContactPoints | p2d.DrawContactNormals:
+					flags = p2d.DrawAll
+				default:
+					flags = p2d.DrawShapes
+				}
+			case 'r':
+				reloaded, err := p2d.LoadScene(opts.scenePath)
+				if err != nil {
+					return err
+				}
+				reloaded.Paused = world.Paused
+				// The stepper and canvas point at world, so this swaps the scene under them
+				world = reloaded
+				c = newCanvas(cols, rows, &world)
+			}
+		case now := <-ticker.C:
+			stepper.Advance(now.Sub(last).Seconds())
+			last = now
+
+			c.clear()
+			world.DebugDraw(c, flags, stepper.Alpha())
+			sb.Reset()
+			sb.WriteString("\x1b[H")
+			c.render(&sb)
+			status := fmt.Sprintf("%d bodies  [space] pause  [n] step  [d] draw  [r] reload  [q] quit", len(world.Bodies))
+			if world.Paused {
+				status = "PAUSED  " + status
+			}
+			if len(status) > cols {
+				status = status[:cols]
+			}
+			sb.WriteString("\x1b[2K" + status)
+			os.Stdout.WriteString(sb.String())
+		}
+	}
+}
+
+// Returns rows then columns, the same order as stty
+func terminalSize() (int, int, error) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("can't get the terminal size (try -cols and -rows): %w", err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("can't read the terminal size from %q", out)
+	}
+	rows, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	cols, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return rows, cols, nil
+}
+
+// Keys should come in as they're pressed without being echoed. Going through stty
+// keeps us away from the platform specific ioctls.
+func rawMode() (func(), error) {
+	stty := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = os.Stdin
+		return cmd.Output()
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin isn't a terminal: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(strings.TrimSpace(string(saved)))
+	}, nil
+}
+
+func readKeys(keys chan<- byte) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := os.Stdin.Read(buf); err != nil {
+			close(keys)
+			return
+		}
+		keys <- buf[0]
+	}
+}
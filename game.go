@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"image/color"
+	"math"
 	"math/rand/v2"
 	"slices"
 
@@ -20,6 +21,8 @@ import (
 const (
 	PixelsPerMeter float64 = 200 // 1000 px world is 5 meters accross
 	MetersPerPixel float64 = 1.0 / PixelsPerMeter
+	PhysicsStep    float64 = 1.0 / 120 // s
+	RecordingPath  string  = "recording.json"
 	worldWidth     float64 = float64(WindowWidth) * MetersPerPixel
 	worldHeight    float64 = float64(WindowHeight) * MetersPerPixel
 )
@@ -29,11 +32,21 @@ type Simulation interface {
 	Draw()
 }
 
+// PhysicsStep is a positive constant, so this can't fail unless someone breaks it
+func newStepper(world *p2d.World) *p2d.Stepper {
+	stepper, err := p2d.NewStepper(world, PhysicsStep)
+	if err != nil {
+		panic(err)
+	}
+	return stepper
+}
+
 ///////////////////////////////////////////////////////////////////////
 
 // The game core is the core of every simulation
 type GameCore struct {
 	physicsWorld    *p2d.World
+	stepper         *p2d.Stepper
 	textColor       color.RGBA
 	backgroundColor color.RGBA
 	colors          []color.RGBA
@@ -42,24 +55,30 @@ type GameCore struct {
 	elapsedSteps    int
 	stopwatchStart  float64
 	avgStepTime     float64
+	selected        *p2d.Body
+	recorder        *p2d.Recorder
+	debugDrawFlags  p2d.DebugDrawFlags
 }
 
 func (c *GameCore) Draw() {
 	rl.BeginDrawing()
 	rl.ClearBackground(c.backgroundColor)
 
+	// Draw between the last two physics steps so that movement looks smooth
+	alpha := c.stepper.Alpha()
+	backend := raylibDebugDraw{make(map[*p2d.Body]color.RGBA, len(c.physicsWorld.Bodies))}
 	for i, body := range c.physicsWorld.Bodies {
 		color := c.colors[i]
-		if body.Shape() == p2d.Polygon {
-			err := drawPolygon(body.Vertices(), color)
-			if err != nil {
-				fmt.Println(err.Error())
-			}
-		} else if body.Shape() == p2d.Ball {
-			rl.DrawCircleV(toRLVec(body.Position()),
-				float32(body.Radius()*PixelsPerMeter),
-				color)
+		if body == c.selected {
+			color = rl.ColorBrightness(color, 0.5)
 		}
+		backend.colors[body] = color
+	}
+	c.physicsWorld.DebugDraw(backend, c.debugDrawFlags, alpha)
+
+	if c.selected != nil && slices.Contains(c.physicsWorld.Bodies, c.selected) {
+		position := c.selected.InterpolatedPosition(alpha)
+		drawVectorArrow(position, position.Add(c.selected.Velocity()))
 	}
 
 	if c.debugMode {
@@ -71,6 +90,10 @@ func (c *GameCore) Draw() {
 		rl.DrawText(performanceString, 10, 10, 20, c.textColor)
 	}
 
+	if c.recorder != nil {
+		rl.DrawText("REC", WindowWidth-70, 10, 30, rl.Red)
+	}
+
 	if c.physicsWorld.Paused {
 		rl.DrawText("PAUSED", (WindowWidth/2)-65, 10, 30, rl.Red)
 		rl.DrawRectangleLinesEx(rl.NewRectangle(0, 0, float32(WindowWidth), float32(WindowHeight)), 5, rl.Red)
@@ -82,10 +105,45 @@ func (c *GameCore) Update(dt float64) {
 	if rl.IsKeyPressed(rl.KeySpace) {
 		c.physicsWorld.Paused = !c.physicsWorld.Paused
 	}
-	c.physicsWorld.UpdatePhysics(dt)
+	// Cycle through showing more and more of what the engine is doing
+	if rl.IsKeyPressed('D') {
+		switch c.debugDrawFlags {
+		case p2d.DrawShapes:
+			c.debugDrawFlags = p2d.DrawShapes | p2d.DrawContactPoints | p2d.DrawContactNormals
+		case p2d.DrawShapes | p2d.DrawContactPoints | p2d.DrawContactNormals:
+			c.debugDrawFlags = p2d.DrawAll
+		default:
+			c.debugDrawFlags = p2d.DrawShapes
+		}
+	}
+	// Select whatever is under the mouse, or nothing
+	if rl.IsMouseButtonPressed(rl.MouseButtonMiddle) {
+		c.selected = nil
+		underMouse := c.physicsWorld.QueryPoint(toP2dVec(rl.GetMousePosition()))
+		if len(underMouse) > 0 {
+			c.selected = underMouse[0]
+		}
+	}
+	// Record everything that happens so it can be replayed with NewReplaySim
+	if rl.IsKeyPressed(rl.KeyF9) {
+		if c.recorder == nil {
+			recorder, err := p2d.NewRecorder(c.physicsWorld)
+			if err != nil {
+				fmt.Println(err.Error())
+			}
+			c.recorder = recorder
+		} else {
+			if err := c.recorder.Stop().Save(RecordingPath); err != nil {
+				fmt.Println(err.Error())
+			}
+			c.recorder = nil
+		}
+	}
+	// Physics runs on a fixed time step no matter what the frame rate is
+	steps := c.stepper.Advance(dt)
 
 	if c.debugMode {
-		c.elapsedSteps += c.physicsWorld.NumSteps()
+		c.elapsedSteps += steps * c.physicsWorld.NumSteps()
 
 		currTime := rl.GetTime()
 		elapsedTime := currTime - c.stopwatchStart
@@ -98,22 +156,20 @@ func (c *GameCore) Update(dt float64) {
 		}
 	}
 
-	deleteSet := map[int]bool{}
-	for i, b := range c.physicsWorld.Bodies {
-		if b.Position().Y() < -5 {
-			deleteSet[i] = true
+	// Go backwards so that deleting doesn't shift the bodies we haven't checked yet
+	for i := len(c.physicsWorld.Bodies) - 1; i >= 0; i-- {
+		if c.physicsWorld.Bodies[i].Position().Y() < -5 {
+			c.physicsWorld.DeleteBody(i)
+			c.colors = slices.Delete(c.colors, i, i+1)
 		}
 	}
-	for i := range deleteSet {
-		c.physicsWorld.Bodies = slices.Delete(c.physicsWorld.Bodies, i, i+1)
-		c.colors = slices.Delete(c.colors, i, i+1)
-	}
 }
 
 /////////////////////////////////////////////////////////////////////////////////
 
 type StackingSim struct {
 	GameCore
+	floor *p2d.Body
 }
 
 func (s *StackingSim) Update(dt float64) {
@@ -125,6 +181,7 @@ func (s *StackingSim) Update(dt float64) {
 			0.5,
 			1,
 		)
+		newBox.SetFriction(s.floor.Friction())
 		s.physicsWorld.AddBody(newBox)
 		s.colors = append(s.colors, rl.SkyBlue)
 	}
@@ -135,12 +192,30 @@ func (s *StackingSim) Update(dt float64) {
 			0.5,
 			1,
 		)
+		newBall.SetFriction(s.floor.Friction())
 		s.physicsWorld.AddBody(newBall)
 		s.colors = append(s.colors, rl.Yellow)
 	}
+	// Turn the floor into a conveyor belt
+	if rl.IsKeyPressed('C') {
+		s.setConveyor(s.floor.SurfaceSpeed() == 0)
+	}
 	s.GameCore.Update(dt)
 }
 
+// The belt can only carry things through friction, so everything only gets friction
+// while it's running. The rest of the time the stack works like it always has.
+func (s *StackingSim) setConveyor(on bool) {
+	speed, static, dynamic := 0.0, 0.0, 0.0
+	if on {
+		speed, static, dynamic = 0.5, 0.6, 0.4
+	}
+	s.floor.SetSurfaceSpeed(speed)
+	for _, body := range s.physicsWorld.Bodies {
+		body.SetFriction(static, dynamic)
+	}
+}
+
 func NewStackingSim() *StackingSim {
 	var bodies []*p2d.Body
 	var colors []color.RGBA
@@ -153,6 +228,7 @@ func NewStackingSim() *StackingSim {
 	return &StackingSim{
 		GameCore{
 			&world,
+			newStepper(&world),
 			rl.NewColor(255, 240, 124, 255),
 			rl.NewColor(13, 27, 42, 255),
 			colors,
@@ -162,7 +238,11 @@ func NewStackingSim() *StackingSim {
 			0,
 			rl.GetTime(),
 			1.0,
+			nil,
+			nil,
+			p2d.DrawShapes,
 		},
+		floor,
 	}
 }
 
@@ -219,6 +299,182 @@ func NewFloatingSim() *FloatingSim {
 	return &FloatingSim{
 		GameCore{
 			&world,
+			newStepper(&world),
+			rl.NewColor(255, 240, 124, 255),
+			rl.NewColor(13, 27, 42, 255),
+			colors,
+			//debug stuff
+			true,
+			0,
+			0,
+			rl.GetTime(),
+			1.0,
+			nil,
+			nil,
+			p2d.DrawShapes,
+		},
+	}
+}
+
+type PlatformerSim struct {
+	GameCore
+	player *p2d.Body
+}
+
+func (s *PlatformerSim) Update(dt float64) {
+	if rl.IsKeyDown(rl.KeyLeft) {
+		s.player.ApplyImpulse(p2d.NewVec2(-8*dt, 0))
+	}
+	if rl.IsKeyDown(rl.KeyRight) {
+		s.player.ApplyImpulse(p2d.NewVec2(8*dt, 0))
+	}
+	// Only jump if we aren't already moving up or down
+	if rl.IsKeyPressed(rl.KeyUp) && math.Abs(s.player.Velocity().Y()) < 0.05 {
+		s.player.ApplyImpulse(p2d.NewVec2(0, 5))
+	}
+	s.GameCore.Update(dt)
+}
+
+func NewPlatformerSim() *PlatformerSim {
+	var bodies []*p2d.Body
+	var colors []color.RGBA
+	floor := p2d.NewBox(p2d.NewVec2(worldWidth/2, 0.15), p2d.NewVec2(worldWidth-0.15, 0.15), 0, 0, 0)
+	floor.SetFriction(0.6, 0.4)
+	bodies = append(bodies, floor)
+	colors = append(colors, rl.Gray)
+
+	// Staircase of platforms that can be jumped through from below
+	for i := range 3 {
+		platform := p2d.NewBox(
+			p2d.NewVec2(worldWidth/4*float64(i+1), 1.0+0.9*float64(i)),
+			p2d.NewVec2(2, 0.1),
+			0,
+			0,
+			0,
+		)
+		platform.SetOneWay(p2d.NewVec2(0, 1))
+		platform.SetFriction(0.6, 0.4)
+		bodies = append(bodies, platform)
+		colors = append(colors, rl.Orange)
+	}
+
+	player := p2d.NewBall(p2d.NewVec2(1, 0.5), 0.15, 0, 1)
+	player.SetFriction(0.6, 0.4)
+	bodies = append(bodies, player)
+	colors = append(colors, rl.Yellow)
+
+	world := p2d.NewWorld(bodies, p2d.NewVec2(worldWidth, worldHeight), 9.8, 50)
+
+	return &PlatformerSim{
+		GameCore{
+			&world,
+			newStepper(&world),
+			rl.NewColor(255, 240, 124, 255),
+			rl.NewColor(13, 27, 42, 255),
+			colors,
+			//debug stuff
+			true,
+			0,
+			0,
+			rl.GetTime(),
+			1.0,
+			nil,
+			nil,
+			p2d.DrawShapes,
+		},
+		player,
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////
+
+// Loads a scene file (see physics2d/scene.go), and saves it back with 'S'
+type SceneSim struct {
+	GameCore
+	path string
+}
+
+func (s *SceneSim) Update(dt float64) {
+	if rl.IsKeyPressed('S') {
+		if err := p2d.SaveScene(s.physicsWorld, s.path); err != nil {
+			fmt.Println(err.Error())
+		}
+	}
+	s.GameCore.Update(dt)
+}
+
+func NewSceneSim(path string) (*SceneSim, error) {
+	world, err := p2d.LoadScene(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var colors []color.RGBA
+	for _, body := range world.Bodies {
+		colors = append(colors, p2d.DebugBodyColor(body))
+	}
+
+	return &SceneSim{
+		GameCore{
+			&world,
+			newStepper(&world),
+			rl.NewColor(255, 240, 124, 255),
+			rl.NewColor(13, 27, 42, 255),
+			colors,
+			//debug stuff
+			true,
+			0,
+			0,
+			rl.GetTime(),
+			1.0,
+			nil,
+			nil,
+			p2d.DrawShapes,
+		},
+		path,
+	}, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+
+// Plays back a recording made with F9. The bodies come and go on their own,
+// so colors are picked from what each body is.
+type ReplaySim struct {
+	GameCore
+	replayer *p2d.Replayer
+}
+
+func (s *ReplaySim) Update(dt float64) {
+	s.GameCore.Update(dt)
+	s.colors = s.colors[:0]
+	for _, body := range s.physicsWorld.Bodies {
+		s.colors = append(s.colors, p2d.DebugBodyColor(body))
+	}
+	if err := s.replayer.Err(); err != nil {
+		fmt.Println(err.Error())
+	}
+}
+
+func NewReplaySim(path string) (*ReplaySim, error) {
+	recording, err := p2d.LoadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	replayer, err := p2d.NewReplayer(recording)
+	if err != nil {
+		return nil, err
+	}
+	world := replayer.World
+
+	var colors []color.RGBA
+	for _, body := range world.Bodies {
+		colors = append(colors, p2d.DebugBodyColor(body))
+	}
+
+	return &ReplaySim{
+		GameCore{
+			world,
+			newStepper(world),
 			rl.NewColor(255, 240, 124, 255),
 			rl.NewColor(13, 27, 42, 255),
 			colors,
@@ -228,10 +484,55 @@ func NewFloatingSim() *FloatingSim {
 			0,
 			rl.GetTime(),
 			1.0,
+			nil,
+			nil,
+			p2d.DrawShapes,
 		},
+		replayer,
+	}, nil
+}
+
+// Draws what the physics world tells it to with raylib. Bodies keep the
+// colors that their sim gave them.
+type raylibDebugDraw struct {
+	colors map[*p2d.Body]color.RGBA
+}
+
+func (d raylibDebugDraw) BodyColor(b *p2d.Body) (color.RGBA, bool) {
+	c, ok := d.colors[b]
+	return c, ok
+}
+
+func (d raylibDebugDraw) DrawPolygon(vertices []p2d.Vec2, color color.RGBA) {
+	err := drawPolygon(vertices, toRLColor(color))
+	if err != nil {
+		fmt.Println(err.Error())
 	}
 }
 
+func (d raylibDebugDraw) DrawCircle(center p2d.Vec2, radius float64, color color.RGBA) {
+	rl.DrawCircleV(toRLVec(center), float32(radius*PixelsPerMeter), toRLColor(color))
+}
+
+func (d raylibDebugDraw) DrawSegment(a, b p2d.Vec2, color color.RGBA) {
+	rl.DrawLineEx(toRLVec(a), toRLVec(b), 2, toRLColor(color))
+}
+
+func (d raylibDebugDraw) DrawPoint(p p2d.Vec2, size float64, color color.RGBA) {
+	rl.DrawCircleV(toRLVec(p), float32(size/2), toRLColor(color))
+}
+
+func (d raylibDebugDraw) DrawTransform(xf p2d.Transform) {
+	axisLength := 0.1 // m
+	rl.DrawLineEx(toRLVec(xf.Pos), toRLVec(xf.Pos.Add(xf.Rotate(p2d.NewVec2(axisLength, 0)))), 2, rl.Red)
+	rl.DrawLineEx(toRLVec(xf.Pos), toRLVec(xf.Pos.Add(xf.Rotate(p2d.NewVec2(0, axisLength)))), 2, rl.Green)
+}
+
+// Raylib wants straight alpha, but image/color (and so physics2d) is premultiplied
+func toRLColor(c color.RGBA) color.RGBA {
+	return color.RGBA(color.NRGBAModel.Convert(c).(color.NRGBA))
+}
+
 func toRLVec(v p2d.Vec2) rl.Vector2 {
 	return rl.Vector2{
 		X: float32(v.X() * PixelsPerMeter),
@@ -35,5 +35,12 @@ func main() {
 
 func createSim() Simulation {
 	// return NewFloatingSim()
+	// return NewPlatformerSim()
+	// if sim, err := NewSceneSim("scenes/stacking.json"); err == nil {
+	// 	return sim
+	// }
+	// if sim, err := NewReplaySim(RecordingPath); err == nil {
+	// 	return sim
+	// }
 	return NewStackingSim()
 }
@@ -1,9 +1,6 @@
 package physics2d
 
-import (
-	"errors"
-	"math"
-)
+import "errors"
 
 type BodyShape uint8
 
@@ -14,129 +11,133 @@ const (
 )
 
 type Body struct {
-	shape                   BodyShape
+	id                      uint64 // Handed out by the world, 0 until then
+	geometry                Shape
 	dimensions              Vec2
-	radius                  float64
-	vertices                []Vec2
 	transformedVertices     []Vec2
+	transform               Transform
 	needTransformUpdate     bool
-	density                 float64
-	position                Vec2    // m
-	velocity                Vec2    // m/s
-	acceleration            Vec2    // m/s2
-	inverseMass             float64 // 1/kg
-	rotation                float64 // rad
-	rotationalVelocity      float64 // rad/s
-	rotationalAcceleration  float64 // rad/s2
-	inverseMomentOfIntertia float64 // 1/kg*m2
-	restitution             float64
-}
-
-func NewBall(position Vec2, radius float64, restitution float64, mass float64) *Body {
-	if radius <= 0 {
-		return nil
+	density                 Scalar
+	position                Vec2   // m
+	velocity                Vec2   // m/s
+	acceleration            Vec2   // m/s2
+	mass                    Scalar // kg, kept so that 1/inverseMass rounding can't change it
+	inverseMass             Scalar // 1/kg
+	rotation                Scalar // rad
+	rotationalVelocity      Scalar // rad/s
+	rotationalAcceleration  Scalar // rad/s2
+	inverseMomentOfIntertia Scalar // 1/kg*m2
+	restitution             Scalar
+	staticFriction          Scalar
+	dynamicFriction         Scalar
+	filter                  Filter
+	sensor                  bool
+	oneWayNormal            Vec2   // Zero unless this is a one-way platform
+	surfaceSpeed            Scalar // m/s
+
+	// Where the body was before the last tick, for interpolating
+	previousPosition Vec2
+	previousRotation Scalar
+}
+
+// Works for any shape. The shape can be shared between bodies, since it never changes.
+func NewBody(shape Shape, position Vec2, rotation Scalar, restitution Scalar, mass Scalar) (*Body, error) {
+	if shape == nil {
+		return nil, errors.New("physics2d: body must have a shape")
 	}
-	if restitution < 0 || restitution > 1 {
-		return nil
+	if restitution < 0 || restitution > one {
+		return nil, errors.New("physics2d: restitution must be between 0 and 1")
 	}
 	if mass < 0 {
-		return nil
+		return nil, errors.New("physics2d: body must have nonnegative mass")
 	}
-	var inverseMass float64
-	var inverseMomentOfIntertia float64
+	var inverseMass Scalar
+	var inverseMomentOfIntertia Scalar
 	if mass == 0 {
 		inverseMass = 0
 		inverseMomentOfIntertia = 0
 	} else {
-		inverseMass = 1.0 / mass
-		inverseMomentOfIntertia = 1.0 / (0.5 * mass * radius * radius)
+		inverseMass = div(one, mass)
+		if momentOfInertia := shape.MomentOfInertia(mass); momentOfInertia > 0 {
+			inverseMomentOfIntertia = div(one, momentOfInertia)
+		}
 	}
+	var density Scalar
+	if area := shape.Area(); area > 0 {
+		density = div(mass, area)
+	}
+
+	// Polygons keep their transformed vertices around so we only transform them once per move
+	var transformedVertices []Vec2
+	if polygon, ok := shape.(*ConvexPolygon); ok {
+		transformedVertices = make([]Vec2, len(polygon.vertices))
+	}
+
+	min, max := shape.AABB(NewTransform(ZeroVec2(), 0))
+	dimensions := max.Sub(min)
+
 	return &Body{
-		shape:                   Ball,
-		dimensions:              Vec2{radius * 2, radius * 2},
-		radius:                  radius,
-		vertices:                nil,
-		transformedVertices:     nil,
+		geometry:                shape,
+		dimensions:              dimensions,
+		transformedVertices:     transformedVertices,
 		needTransformUpdate:     true,
-		density:                 mass / (math.Pi * radius * radius),
+		density:                 density,
 		position:                position,
 		velocity:                Vec2{0, 0},
 		acceleration:            Vec2{0, 0},
+		mass:                    mass,
 		inverseMass:             inverseMass,
-		rotation:                0,
+		rotation:                rotation,
 		rotationalVelocity:      0,
 		rotationalAcceleration:  0,
 		inverseMomentOfIntertia: inverseMomentOfIntertia,
 		restitution:             restitution,
-	}
+		filter:                  DefaultFilter(),
+		previousPosition:        position,
+		previousRotation:        rotation,
+	}, nil
 }
 
-func NewBox(position Vec2, dimensions Vec2, rotation float64, restitution float64, mass float64) *Body {
-	if dimensions.x <= 0 || dimensions.y <= 0 {
+func NewBall(position Vec2, radius Scalar, restitution Scalar, mass Scalar) *Body {
+	if radius <= 0 {
 		return nil
 	}
-	if restitution < 0 || restitution > 1 {
+	b, err := NewBody(&Circle{radius}, position, 0, restitution, mass)
+	if err != nil {
 		return nil
 	}
-	if mass < 0 {
+	return b
+}
+
+func NewBox(position Vec2, dimensions Vec2, rotation Scalar, restitution Scalar, mass Scalar) *Body {
+	if dimensions.x <= 0 || dimensions.y <= 0 {
 		return nil
 	}
-	var inverseMass float64
-	var inverseMomentOfIntertia float64
-	if mass == 0 {
-		inverseMass = 0
-		inverseMomentOfIntertia = 0
-	} else {
-		inverseMass = 1.0 / mass
-		inverseMomentOfIntertia = 1.0 / ((1.0 / 12.0) * mass * (dimensions.x*dimensions.x + dimensions.y*dimensions.y))
+	return NewPolygon(position, boxVertieces(dimensions), rotation, restitution, mass)
+}
+
+// Any convex polygon. The position is where its centroid ends up.
+func NewPolygon(position Vec2, vertices []Vec2, rotation Scalar, restitution Scalar, mass Scalar) *Body {
+	polygon, err := NewConvexPolygon(vertices)
+	if err != nil {
+		return nil
 	}
-	return &Body{
-		shape:                   Polygon,
-		dimensions:              dimensions,
-		radius:                  dimensions.x / 2,
-		vertices:                boxVertieces(dimensions),
-		transformedVertices:     make([]Vec2, 4),
-		needTransformUpdate:     true,
-		density:                 mass / (dimensions.x * dimensions.y),
-		position:                position,
-		velocity:                Vec2{0, 0},
-		acceleration:            Vec2{0, 0},
-		inverseMass:             inverseMass,
-		rotation:                rotation,
-		rotationalVelocity:      0,
-		rotationalAcceleration:  0,
-		inverseMomentOfIntertia: inverseMomentOfIntertia,
-		restitution:             restitution,
+	b, err := NewBody(polygon, position, rotation, restitution, mass)
+	if err != nil {
+		return nil
 	}
+	return b
 }
 
-func NewPointMass(position Vec2, mass float64) (*Body, error) {
+func NewPointMass(position Vec2, mass Scalar) (*Body, error) {
 	if mass < 0 {
 		return nil, errors.New("physics2d: box must have nonnegative mass")
 	}
-	var inverseMass float64
-	if mass == 0 {
-		inverseMass = 0
-	} else {
-		inverseMass = 1.0 / mass
+	b, err := NewBody(&Point{}, position, 0, 0, mass)
+	if err != nil {
+		return nil, err
 	}
-	return &Body{
-		shape:                   PointMass,
-		dimensions:              Vec2{0, 0},
-		radius:                  0,
-		vertices:                nil,
-		transformedVertices:     nil,
-		needTransformUpdate:     false,
-		position:                position,
-		velocity:                Vec2{0, 0},
-		acceleration:            Vec2{0, 0},
-		inverseMass:             inverseMass,
-		rotation:                0,
-		rotationalVelocity:      0,
-		rotationalAcceleration:  0,
-		inverseMomentOfIntertia: 0,
-		restitution:             0,
-	}, nil
+	return b, nil
 }
 
 func boxVertieces(dim Vec2) []Vec2 {
@@ -152,78 +153,215 @@ func boxVertieces(dim Vec2) []Vec2 {
 	}
 }
 
+// Copies the body so that the copy can be moved around without touching the original
+func (b *Body) clone() *Body {
+	c := *b
+	if b.transformedVertices != nil {
+		c.transformedVertices = make([]Vec2, len(b.transformedVertices))
+		c.needTransformUpdate = true
+	}
+	return &c
+}
+
 // Expose some getters so we can draw everything. They are read only outside the package
 // since the physics should only be controlled from inside the engine
 
+// Unique within a world and stays the same for the body's whole life. 0 if the
+// body hasn't been added to a world yet.
+func (b *Body) ID() uint64 {
+	return b.id
+}
+
 func (b *Body) Shape() BodyShape {
-	return b.shape
+	return b.geometry.Type()
 }
 
-func (b *Body) Radius() float64 {
-	return b.radius
+// Half the width for anything that isn't a ball
+func (b *Body) Radius() Scalar {
+	if circle, ok := b.geometry.(*Circle); ok {
+		return circle.Radius
+	}
+	return b.dimensions.x / 2
 }
 
-// Only expose the most recently transformed vertices
-func (b *Body) Vertices() []Vec2 {
+// Polygon vertices in local space, nil for every other shape
+func (b *Body) localVertices() []Vec2 {
+	if polygon, ok := b.geometry.(*ConvexPolygon); ok {
+		return polygon.vertices
+	}
+	return nil
+}
+
+func (b *Body) Geometry() Shape {
+	return b.geometry
+}
+
+// Where the shape is in the world, only recalculated after the body moves
+func (b *Body) Transform() Transform {
 	if b.needTransformUpdate {
-		transform := newTransform(b.position, b.rotation)
+		b.setTransform(NewTransform(b.position, b.rotation))
+	}
 
-		for i, v := range b.vertices {
-			b.transformedVertices[i] = v.Transform(transform)
-		}
-		b.needTransformUpdate = false
+	return b.transform
+}
+
+// Moves the polygon vertices along with the transform
+func (b *Body) setTransform(xf Transform) {
+	b.transform = xf
+	for i, v := range b.localVertices() {
+		b.transformedVertices[i] = v.Transform(xf)
 	}
+	b.needTransformUpdate = false
+}
 
+// Only expose the most recently transformed vertices
+func (b *Body) Vertices() []Vec2 {
+	b.Transform()
 	return b.transformedVertices
 }
 
+// Axis aligned bounding box, returned as its bottom left and top right corners
+func (b *Body) AABB() (Vec2, Vec2) {
+	return b.geometry.AABB(b.Transform())
+}
+
+// The point on the body that's furthest in the direction, so bodies can be used with GJK
+func (b *Body) Support(direction Vec2) Vec2 {
+	if b.transformedVertices == nil {
+		return b.geometry.Support(b.Transform(), direction)
+	}
+
+	// Polygons already have their vertices in world space
+	vertices := b.Vertices()
+	furthest := vertices[0]
+	for _, v := range vertices[1:] {
+		if v.Dot(direction) > furthest.Dot(direction) {
+			furthest = v
+		}
+	}
+	return furthest
+}
+
 func (b *Body) Position() Vec2 {
 	return b.position
 }
 
+func (b *Body) Rotation() Scalar {
+	return b.rotation
+}
+
+// Blends between where the body was before the last tick and where it is now.
+// Use with Stepper.Alpha to draw smoothly between fixed steps.
+func (b *Body) InterpolatedPosition(alpha Scalar) Vec2 {
+	return b.previousPosition.ScaleMult(one - alpha).Add(b.position.ScaleMult(alpha))
+}
+
+func (b *Body) InterpolatedRotation(alpha Scalar) Scalar {
+	return mul(b.previousRotation, one-alpha) + mul(b.rotation, alpha)
+}
+
+// Makes new vertices every call, so only use it for drawing
+func (b *Body) InterpolatedVertices(alpha Scalar) []Vec2 {
+	transform := NewTransform(b.InterpolatedPosition(alpha), b.InterpolatedRotation(alpha))
+	local := b.localVertices()
+	vertices := make([]Vec2, len(local))
+	for i, v := range local {
+		vertices[i] = v.Transform(transform)
+	}
+	return vertices
+}
+
 func (b *Body) Velocity() Vec2 {
 	return b.velocity
 }
 
-func (b *Body) RotationalVelocity() float64 {
+func (b *Body) RotationalVelocity() Scalar {
 	return b.rotationalVelocity
 }
 
-func (b *Body) Density() float64 {
+func (b *Body) Density() Scalar {
 	return b.density
 }
 
-func (b *Body) Mass() float64 {
-	if b.inverseMass == 0 {
-		return 0
-	} else {
-		return 1.0 / b.inverseMass
-	}
+func (b *Body) Mass() Scalar {
+	return b.mass
 }
 
-func (b *Body) MomentOfIntertia() float64 {
+func (b *Body) MomentOfIntertia() Scalar {
 	if b.inverseMomentOfIntertia == 0 {
 		return 0
 	} else {
-		return 1.0 / b.inverseMomentOfIntertia
+		return div(one, b.inverseMomentOfIntertia)
 	}
 }
 
-// Integrate the acceleration/velocity over time to determine new velocity and position
-func (b *Body) Update(dt float64) {
-	if b.inverseMass == 0 {
+func (b *Body) Restitution() Scalar {
+	return b.restitution
+}
+
+func (b *Body) Friction() (Scalar, Scalar) {
+	return b.staticFriction, b.dynamicFriction
+}
+
+// Bodies start out frictionless. Static friction should be at least as big as dynamic friction.
+func (b *Body) SetFriction(staticFriction, dynamicFriction Scalar) {
+	b.staticFriction = staticFriction
+	b.dynamicFriction = dynamicFriction
+}
+
+func (b *Body) SurfaceSpeed() Scalar {
+	return b.surfaceSpeed
+}
+
+// Makes the surface act like a conveyor belt, dragging touching bodies along through friction.
+// Positive speeds move the surface clockwise around the body, so the top of a floor moves right.
+func (b *Body) SetSurfaceSpeed(speed Scalar) {
+	b.surfaceSpeed = speed
+}
+
+func (b *Body) Filter() Filter {
+	return b.filter
+}
+
+// The filter can be changed at any time, like when a projectile leaves its shooter
+func (b *Body) SetFilter(filter Filter) {
+	b.filter = filter
+}
+
+func (b *Body) IsSensor() bool {
+	return b.sensor
+}
+
+// Sensors report overlaps in World.SensorEvents but are never pushed around
+// by collisions, and they never push anything else either
+func (b *Body) SetSensor(sensor bool) {
+	b.sensor = sensor
+}
+
+// One-way platforms only collide with bodies on the side that the normal points
+// towards, so things can jump up through them and then land on top
+func (b *Body) SetOneWay(normal Vec2) {
+	if normal.LengthSquared() == 0 {
+		b.oneWayNormal = ZeroVec2()
 		return
 	}
+	b.oneWayNormal = normal.Normalize()
+}
 
-	b.velocity = b.velocity.Add(b.acceleration.ScaleMult(dt))
-	b.rotationalVelocity += b.rotationalAcceleration * dt
+func (b *Body) OneWay() (Vec2, bool) {
+	return b.oneWayNormal, b.oneWayNormal.LengthSquared() > 0
+}
 
-	b.Move(b.velocity.ScaleMult(dt))
-	b.Rotate(b.rotationalVelocity * dt)
+// Integrate the acceleration/velocity over time to determine new velocity and position
+func (b *Body) Update(dt Scalar) {
+	if b.inverseMass == 0 {
+		return
+	}
 
-	// Acceleration is reevaluated every tick
-	b.acceleration = ZeroVec2()
-	b.rotationalAcceleration = 0
+	acceleration := b.acceleration
+	b.integrate(SemiImplicitEuler{}, dt, func(Vec2, Vec2) Vec2 {
+		return acceleration
+	})
 }
 
 func (b *Body) Move(displacement Vec2) {
@@ -247,24 +385,29 @@ func (b *Body) ApplyForce(force Vec2) {
 	b.acceleration = b.acceleration.Add(force.ScaleMult(b.inverseMass))
 }
 
+// Instantly changes the velocity, impulse is in Newton-seconds
+func (b *Body) ApplyImpulse(impulse Vec2) {
+	b.velocity = b.velocity.Add(impulse.ScaleMult(b.inverseMass))
+}
+
 // Instantaneous torque in Newton-meters
-func (b *Body) ApplyTorque(torque float64) {
-	b.rotationalAcceleration += torque * b.inverseMomentOfIntertia
+func (b *Body) ApplyTorque(torque Scalar) {
+	b.rotationalAcceleration += mul(torque, b.inverseMomentOfIntertia)
 }
 
 // Applies the linear component of a force and its moment
 func (b *Body) ApplyPositionalForce(force Vec2, position Vec2) {
 	b.acceleration = b.acceleration.Add(force.ScaleMult(b.inverseMass))
-	b.rotationalAcceleration += position.Cross(force) * b.inverseMomentOfIntertia
+	b.rotationalAcceleration += mul(position.Cross(force), b.inverseMomentOfIntertia)
 }
 
 // A positive rotation is counter-clockwise (positive Z by RHR)
-func (b *Body) Rotate(rotationalDisplacement float64) {
+func (b *Body) Rotate(rotationalDisplacement Scalar) {
 	b.rotation += rotationalDisplacement
 	b.needTransformUpdate = true
 }
 
-func (b *Body) RotateTo(rotation float64) {
+func (b *Body) RotateTo(rotation Scalar) {
 	b.rotation = rotation
 	b.needTransformUpdate = true
 }
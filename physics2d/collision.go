@@ -1,19 +1,111 @@
 package physics2d
 
-import (
-	"fmt"
-	"math"
-)
+import "fmt"
 
 // Normal is normalized and in the a->b direction
 type Collision struct {
 	a      *Body
 	b      *Body
 	normal Vec2
-	depth  float64
+	depth  Scalar
+
+	// These start out mixed from both bodies, but can be changed in PreSolve
+	enabled         bool
+	restitution     Scalar
+	staticFriction  Scalar
+	dynamicFriction Scalar
+
+	// Set once a body starts passing through a one-way platform, so it can finish
+	passingThrough bool
+
+	// Filled in by Resolve
+	contactPoints  []Vec2
+	relativeSpeed  Scalar
+	normalImpulse  Scalar
+	tangentImpulse Scalar
+}
+
+func newCollision(a, b *Body, normal Vec2, depth Scalar) *Collision {
+	return &Collision{
+		a:               a,
+		b:               b,
+		normal:          normal,
+		depth:           depth,
+		enabled:         true,
+		restitution:     min(a.restitution, b.restitution),
+		staticFriction:  sqrt(mul(a.staticFriction, b.staticFriction)),
+		dynamicFriction: sqrt(mul(a.dynamicFriction, b.dynamicFriction)),
+	}
+}
+
+// Collisions are read only outside the package, except for what PreSolve is allowed to change
+
+func (c *Collision) A() *Body {
+	return c.a
+}
+
+func (c *Collision) B() *Body {
+	return c.b
+}
+
+// Normalized and in the a->b direction
+func (c *Collision) Normal() Vec2 {
+	return c.normal
+}
+
+// How far the bodies were overlapping when the collision was found
+func (c *Collision) Depth() Scalar {
+	return c.depth
+}
+
+// One or two points where the bodies touch. Resolve finds these after pulling the bodies
+// apart, so they're only estimates for collisions that were never resolved.
+func (c *Collision) ContactPoints() []Vec2 {
+	if c.contactPoints == nil {
+		c.contactPoints = collisionPoints(c)
+	}
+	return c.contactPoints
+}
+
+// How fast the contact points were moving towards each other before Resolve, in m/s.
+// This is what you want for impact sounds and damage.
+func (c *Collision) RelativeSpeed() Scalar {
+	return c.relativeSpeed
+}
+
+// Impulses in N*s applied to b by Resolve. a gets the opposite impulses.
+func (c *Collision) NormalImpulse() Scalar {
+	return c.normalImpulse
+}
+
+func (c *Collision) TangentImpulse() Scalar {
+	return c.tangentImpulse
+}
+
+// A disabled collision is still detected, but Resolve won't do anything with it
+func (c *Collision) SetEnabled(enabled bool) {
+	c.enabled = enabled
+}
+
+func (c *Collision) Enabled() bool {
+	return c.enabled
+}
+
+func (c *Collision) SetRestitution(restitution Scalar) {
+	c.restitution = restitution
+}
+
+func (c *Collision) SetFriction(staticFriction, dynamicFriction Scalar) {
+	c.staticFriction = staticFriction
+	c.dynamicFriction = dynamicFriction
 }
 
 func (c *Collision) Resolve() {
+	c.normalImpulse = 0
+	c.tangentImpulse = 0
+	if !c.enabled {
+		return
+	}
 
 	// Start by separating bodies
 	if c.a.inverseMass == 0 {
@@ -27,6 +119,7 @@ func (c *Collision) Resolve() {
 
 	// We can find accurate collision points now that they are barely touching
 	cps := collisionPoints(c)
+	c.contactPoints = cps
 	var cp Vec2
 	if len(cps) > 1 {
 		cp = Midpoint(cps[0], cps[1])
@@ -37,70 +130,140 @@ func (c *Collision) Resolve() {
 	rA := cp.Sub(c.a.position)
 	rB := cp.Sub(c.b.position)
 
-	// Cross product is wacky in 2d
-	aCPVel := NewVec2(c.a.velocity.x-rA.y*c.a.rotationalVelocity, c.a.velocity.y+rA.x*c.a.rotationalVelocity)
-	bCPVel := NewVec2(c.b.velocity.x-rB.y*c.b.rotationalVelocity, c.b.velocity.y+rB.x*c.b.rotationalVelocity)
-
 	// Relative velocity should be based on collision point
-	relativeVelocity := bCPVel.Sub(aCPVel)
+	relativeVelocity := contactVelocity(c.b, rB).Sub(contactVelocity(c.a, rA))
 	rVelDotNormal := relativeVelocity.Dot(c.normal)
+	c.relativeSpeed = -rVelDotNormal
 
 	if rVelDotNormal > 0.0 {
 		// objects are separating
 		return
 	}
 
-	e := math.Min(c.a.restitution, c.b.restitution)
+	e := c.restitution
 
 	rA_perp := rA.Perpendicular()
 	rB_perp := rB.Perpendicular()
 
-	j := (-(1.0 + e) * rVelDotNormal) / ((c.a.inverseMass + c.b.inverseMass) +
-		(rA_perp.Dot(c.normal) * rA_perp.Dot(c.normal) * c.a.inverseMomentOfIntertia) +
-		(rB_perp.Dot(c.normal) * rB_perp.Dot(c.normal) * c.b.inverseMomentOfIntertia))
+	j := div(mul(-(one+e), rVelDotNormal), (c.a.inverseMass+c.b.inverseMass)+
+		mul(mul(rA_perp.Dot(c.normal), rA_perp.Dot(c.normal)), c.a.inverseMomentOfIntertia)+
+		mul(mul(rB_perp.Dot(c.normal), rB_perp.Dot(c.normal)), c.b.inverseMomentOfIntertia))
+
+	c.applyImpulse(c.normal.ScaleMult(j), rA_perp, rB_perp)
+	c.normalImpulse = j
+
+	if c.staticFriction == 0 && c.dynamicFriction == 0 {
+		return
+	}
+
+	// Friction works the same way, but along the surface instead of the normal.
+	// The velocities changed, so we need a new relative velocity.
+	tangent := NewVec2(c.normal.y, -c.normal.x)
+	relativeVelocity = contactVelocity(c.b, rB).Sub(contactVelocity(c.a, rA))
+	rVelDotTangent := relativeVelocity.Dot(tangent)
+
+	// Moving surfaces (conveyor belts) want the bodies to slide past each other.
+	// The tangent is clockwise around a, but counter-clockwise around b.
+	rVelDotTangent -= c.a.surfaceSpeed + c.b.surfaceSpeed
 
-	c.a.velocity = c.a.velocity.Add(c.normal.ScaleMult(-j * c.a.inverseMass))
-	c.b.velocity = c.b.velocity.Add(c.normal.ScaleMult(j * c.b.inverseMass))
+	jt := div(-rVelDotTangent, (c.a.inverseMass+c.b.inverseMass)+
+		mul(mul(rA_perp.Dot(tangent), rA_perp.Dot(tangent)), c.a.inverseMomentOfIntertia)+
+		mul(mul(rB_perp.Dot(tangent), rB_perp.Dot(tangent)), c.b.inverseMomentOfIntertia))
 
-	c.a.rotationalVelocity += rA_perp.Dot(c.normal.ScaleMult(-j)) * c.a.inverseMomentOfIntertia
-	c.b.rotationalVelocity += rB_perp.Dot(c.normal.ScaleMult(j)) * c.b.inverseMomentOfIntertia
+	// Coulomb's law: if static friction can't stop the sliding, dynamic friction slows it down
+	if abs(jt) > mul(j, c.staticFriction) {
+		jt = copysign(mul(j, c.dynamicFriction), jt)
+	}
+
+	c.applyImpulse(tangent.ScaleMult(jt), rA_perp, rB_perp)
+	c.tangentImpulse = jt
+}
+
+// Cross product is wacky in 2d
+func contactVelocity(b *Body, r Vec2) Vec2 {
+	return NewVec2(b.velocity.x-mul(r.y, b.rotationalVelocity), b.velocity.y+mul(r.x, b.rotationalVelocity))
+}
+
+// The impulse is applied to b, and the opposite impulse is applied to a
+func (c *Collision) applyImpulse(impulse Vec2, rA_perp Vec2, rB_perp Vec2) {
+	c.a.velocity = c.a.velocity.Add(impulse.ScaleMult(-c.a.inverseMass))
+	c.b.velocity = c.b.velocity.Add(impulse.ScaleMult(c.b.inverseMass))
+
+	c.a.rotationalVelocity += mul(rA_perp.Dot(impulse.ScaleMult(-one)), c.a.inverseMomentOfIntertia)
+	c.b.rotationalVelocity += mul(rB_perp.Dot(impulse), c.b.inverseMomentOfIntertia)
+}
+
+// Narrow phase routines for a pair of shape types. Collide only has to handle its
+// shapes in the order they were registered, the other order is taken care of.
+type CollideFunc func(a, b *Body) (*Collision, error)
+
+// Finds where the bodies of a collision touch, after Resolve has pulled them apart
+type ContactPointsFunc func(c *Collision) []Vec2
+
+type narrowPhase struct {
+	collide       CollideFunc
+	contactPoints ContactPointsFunc
+}
+
+var narrowPhases = map[[2]BodyShape]narrowPhase{}
+
+func init() {
+	RegisterCollider(Ball, Ball, ballsCollide, ballContactPoint)
+	RegisterCollider(Ball, Polygon, ballAndPolygonCollide, ballContactPoint)
+	RegisterCollider(Polygon, Polygon, polygonsCollide, polygonContactPoints)
+}
+
+// Adds (or replaces) the routines used between two types of shape. Pairs that don't have
+// any fall back on GJK and EPA. A nil contactPoints also falls back on GJK.
+//
+// The table isn't locked, so only call this from an init function, before any world steps.
+func RegisterCollider(a, b BodyShape, collide CollideFunc, contactPoints ContactPointsFunc) {
+	if contactPoints == nil {
+		contactPoints = gjkContactPoints
+	}
+	narrowPhases[[2]BodyShape{a, b}] = narrowPhase{collide, contactPoints}
 }
 
 func Collide(a, b *Body) (*Collision, error) {
-	switch a.shape {
-	case Ball:
-		switch b.shape {
-		case Ball:
-			return ballsCollide(a, b)
-		case Polygon:
-			return ballAndPolygonCollide(a, b)
-		}
-		return nil, fmt.Errorf("collision: %d is not a valid body shape", b.shape)
-	case Polygon:
-		switch b.shape {
-		case Polygon:
-			return polygonsCollide(a, b)
-		case Ball:
-			return ballAndPolygonCollide(b, a)
-		}
-		return nil, fmt.Errorf("collision: %d is not a valid body shape", b.shape)
+	if a.geometry == nil || b.geometry == nil {
+		return nil, fmt.Errorf("collision: body has no shape")
 	}
-	return nil, fmt.Errorf("collision: %d is not a valid body shape", a.shape)
+	if np, ok := narrowPhases[[2]BodyShape{a.Shape(), b.Shape()}]; ok {
+		return np.collide(a, b)
+	}
+	if np, ok := narrowPhases[[2]BodyShape{b.Shape(), a.Shape()}]; ok {
+		return np.collide(b, a)
+	}
+	// Points have no area, so they don't collide unless someone registers a collider for them
+	if a.Shape() == PointMass || b.Shape() == PointMass {
+		return nil, nil
+	}
+	return gjkCollide(a, b)
 }
 
 func collisionPoints(c *Collision) []Vec2 {
-	if c.a.shape == Ball { // If a is a ball, we dont care what b is
-		// Balls can only contact other objects at one point
-		return []Vec2{c.a.position.Add(c.normal.ScaleMult(c.a.radius))}
+	if np, ok := narrowPhases[[2]BodyShape{c.a.Shape(), c.b.Shape()}]; ok {
+		return np.contactPoints(c)
+	}
+	if np, ok := narrowPhases[[2]BodyShape{c.b.Shape(), c.a.Shape()}]; ok {
+		return np.contactPoints(c)
 	}
-	// Otherwise, both are definitely polygons
+	return gjkContactPoints(c)
+}
+
+// Balls can only contact other objects at one point
+func ballContactPoint(c *Collision) []Vec2 {
+	return []Vec2{c.a.position.Add(c.normal.ScaleMult(c.a.Radius()))}
+}
+
+func polygonContactPoints(c *Collision) []Vec2 {
 	cp1 := ZeroVec2()
 	cp2 := ZeroVec2()
 	numContacts := 0
 
 	aVerts := c.a.Vertices()
 	bVerts := c.b.Vertices()
-	minDistSquared := math.Inf(1)
+	minDistSquared := maxScalar
 	for _, aVert := range aVerts {
 		for j, bEdgeStart := range bVerts {
 			bEdgeEnd := bVerts[(j+1)%len(bVerts)]
@@ -142,20 +305,41 @@ func collisionPoints(c *Collision) []Vec2 {
 	return []Vec2{cp1, cp2}
 }
 
+// Slower than the special cases, but works for any two convex shapes
+func gjkCollide(a, b *Body) (*Collision, error) {
+	penetration, ok := EPAPenetration(a, b)
+	if !ok || penetration.Depth <= 0 {
+		return nil, nil
+	}
+	return newCollision(a, b, penetration.Normal, penetration.Depth), nil
+}
+
+// Resolve leaves the bodies just touching, so the closest points are where they touch
+func gjkContactPoints(c *Collision) []Vec2 {
+	distance := GJKDistance(c.a, c.b)
+	if !distance.Overlapping {
+		return []Vec2{Midpoint(distance.PointA, distance.PointB)}
+	}
+	if penetration, ok := EPAPenetration(c.a, c.b); ok {
+		return []Vec2{Midpoint(penetration.PointA, penetration.PointB)}
+	}
+	return []Vec2{Midpoint(c.a.position, c.b.position)}
+}
+
 // Gets the point on the segment VW that is closest to P, and its distance(squared) from P
-func ClosestPointOnSegment(p, v, w Vec2) (Vec2, float64) {
+func ClosestPointOnSegment(p, v, w Vec2) (Vec2, Scalar) {
 	vw := w.Sub(v)
 	vp := p.Sub(v)
 
 	proj := vp.Dot(vw)
 	lenVWSq := vw.LengthSquared()
 
-	d := proj / lenVWSq
+	d := div(proj, lenVWSq)
 
 	var cp Vec2
 	if d <= 0 {
 		cp = v
-	} else if d >= 1 {
+	} else if d >= one {
 		cp = w
 	} else {
 		cp = v.Add(vw.ScaleMult(d))
@@ -165,21 +349,21 @@ func ClosestPointOnSegment(p, v, w Vec2) (Vec2, float64) {
 }
 
 // Gets the min and max of all points projected onto the axis
-func projectVertecies(vertices []Vec2, axis Vec2) (float64, float64) {
-	min := math.MaxFloat64
-	max := -math.MaxFloat64
+func projectVertecies(vertices []Vec2, axis Vec2) (Scalar, Scalar) {
+	lowest := maxScalar
+	highest := -maxScalar
 
 	for _, v := range vertices {
 		proj := v.Dot(axis)
-		min = math.Min(min, proj)
-		max = math.Max(max, proj)
+		lowest = min(lowest, proj)
+		highest = max(highest, proj)
 	}
 
-	return min, max
+	return lowest, highest
 }
 
 // Gets the min and max points of the circle edge projected onto the axis
-func projectCircle(position Vec2, radius float64, axis Vec2) (float64, float64) {
+func projectCircle(position Vec2, radius Scalar, axis Vec2) (Scalar, Scalar) {
 	centerProj := position.Dot(axis)
 	min := centerProj - radius
 	max := centerProj + radius
@@ -190,7 +374,7 @@ func projectCircle(position Vec2, radius float64, axis Vec2) (float64, float64)
 // Gets the index into the slice of the closest vertex to the given point
 func closestVertexIdx(position Vec2, vertices []Vec2) int {
 	closestIndex := -1
-	minDistSquared := math.MaxFloat64
+	minDistSquared := maxScalar
 	for i, v := range vertices {
 		distSquared := position.DistanceSquared(v)
 		if distSquared < minDistSquared {
@@ -203,26 +387,26 @@ func closestVertexIdx(position Vec2, vertices []Vec2) int {
 }
 
 func ballsCollide(a, b *Body) (*Collision, error) {
-	bothRad := a.radius + b.radius
+	bothRad := a.Radius() + b.Radius()
 	distSquared := a.position.DistanceSquared(b.position)
 
-	if distSquared >= (bothRad * bothRad) {
+	if distSquared >= mul(bothRad, bothRad) {
 		return nil, nil
 	}
 
 	// Only do expensive operations when collision is confirmed
-	distance := math.Sqrt(distSquared)
+	distance := sqrt(distSquared)
 	depth := bothRad - distance
 	displacement := b.position.Sub(a.position)
 	normal := displacement.Normalize()
 
-	return &Collision{a, b, normal, depth}, nil
+	return newCollision(a, b, normal, depth), nil
 }
 
 // SAT only works for convex polygons
 func polygonsCollide(a, b *Body) (*Collision, error) {
 	normal := ZeroVec2()
-	depth := math.MaxFloat64
+	depth := maxScalar
 
 	aVertices := a.Vertices()
 	bVertices := b.Vertices()
@@ -243,7 +427,7 @@ func polygonsCollide(a, b *Body) (*Collision, error) {
 			return nil, nil
 		}
 
-		axisDepth := math.Min(bMax-aMin, aMax-bMin)
+		axisDepth := min(bMax-aMin, aMax-bMin)
 
 		if axisDepth < depth {
 			depth = axisDepth
@@ -270,7 +454,7 @@ func polygonsCollide(a, b *Body) (*Collision, error) {
 			return nil, nil
 		}
 
-		axisDepth := math.Min(bMax-aMin, aMax-bMin)
+		axisDepth := min(bMax-aMin, aMax-bMin)
 		if axisDepth < depth {
 			depth = axisDepth
 			normal = axis
@@ -279,31 +463,31 @@ func polygonsCollide(a, b *Body) (*Collision, error) {
 
 	// Ensure that normal points a->b
 	if b.position.Sub(a.position).Dot(normal) < 0.0 {
-		normal = normal.ScaleMult(-1)
+		normal = normal.ScaleMult(-one)
 	}
 
-	return &Collision{a, b, normal, depth}, nil
+	return newCollision(a, b, normal, depth), nil
 }
 
 func ballAndPolygonCollide(ball, polygon *Body) (*Collision, error) {
 	vertices := polygon.Vertices()
 
 	normal := ZeroVec2()
-	depth := math.MaxFloat64
+	depth := maxScalar
 
 	// Check for a SA between the circles edge to closest vertex
 	closestVertex := vertices[closestVertexIdx(ball.position, vertices)]
 	axis := closestVertex.Sub(ball.position).Normalize()
 
 	pMin, pMax := projectVertecies(vertices, axis)
-	bMin, bMax := projectCircle(ball.position, ball.radius, axis)
+	bMin, bMax := projectCircle(ball.position, ball.Radius(), axis)
 
 	if pMin >= bMax || bMin >= pMax {
 		// Found separating axis
 		return nil, nil
 	}
 
-	axisDepth := math.Min(bMax-pMin, pMax-bMin)
+	axisDepth := min(bMax-pMin, pMax-bMin)
 	if axisDepth < depth {
 		depth = axisDepth
 		normal = axis
@@ -318,7 +502,7 @@ func ballAndPolygonCollide(ball, polygon *Body) (*Collision, error) {
 		edge := vNext.Sub(vCurr)
 		axis := edge.Perpendicular().Normalize()
 
-		bMin, bMax := projectCircle(ball.position, ball.radius, axis)
+		bMin, bMax := projectCircle(ball.position, ball.Radius(), axis)
 		pMin, pMax := projectVertecies(vertices, axis)
 
 		if pMin >= bMax || bMin >= pMax {
@@ -326,7 +510,7 @@ func ballAndPolygonCollide(ball, polygon *Body) (*Collision, error) {
 			return nil, nil
 		}
 
-		axisDepth := math.Min(bMax-pMin, pMax-bMin)
+		axisDepth := min(bMax-pMin, pMax-bMin)
 		if axisDepth < depth {
 			depth = axisDepth
 			normal = axis
@@ -335,8 +519,8 @@ func ballAndPolygonCollide(ball, polygon *Body) (*Collision, error) {
 
 	// Ensure that normal points a->b
 	if polygon.position.Sub(ball.position).Dot(normal) < 0.0 {
-		normal = normal.ScaleMult(-1)
+		normal = normal.ScaleMult(-one)
 	}
 
-	return &Collision{ball, polygon, normal, depth}, nil
+	return newCollision(ball, polygon, normal, depth), nil
 }
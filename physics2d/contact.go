@@ -0,0 +1,95 @@
+package physics2d
+
+// A ContactListener gets told about every solid collision in the world.
+// Sensors don't go through here, they report to World.SensorEvents instead.
+//
+// Begin and end are reported once per tick, no matter how many time steps the
+// bodies spend touching. PreSolve and PostSolve are called every time step.
+type ContactListener interface {
+	// The bodies started touching this tick
+	BeginContact(c *Collision)
+
+	// The bodies were touching last tick, but not anymore. c is the last
+	// collision that was found between them.
+	EndContact(c *Collision)
+
+	// Called right before the collision is resolved. Use SetEnabled, SetRestitution
+	// and SetFriction to change how (or if) it gets resolved.
+	PreSolve(c *Collision)
+
+	// Called after the collision is resolved with the impulses that were applied to b.
+	// a gets the opposite impulses.
+	PostSolve(c *Collision, normalImpulse, tangentImpulse Scalar)
+}
+
+// Remembers the latest collision of every touching pair during the tick
+func (w *World) addContact(c *Collision) {
+	pair := bodyPair{c.a, c.b}
+	if i, ok := w.contactIndex[pair]; ok {
+		c.passingThrough = w.contactBuffer[i].passingThrough
+		w.contactBuffer[i] = c
+		return
+	}
+	// Worlds that didn't come from NewWorld don't have their maps yet
+	if w.contactIndex == nil {
+		w.contactIndex = make(map[bodyPair]int)
+	}
+	w.contactIndex[pair] = len(w.contactBuffer)
+	w.contactBuffer = append(w.contactBuffer, c)
+
+	if i, ok := w.lastContactIndex[pair]; ok {
+		c.passingThrough = w.contacts[i].passingThrough
+	} else if w.Listener != nil {
+		w.Listener.BeginContact(c)
+	}
+}
+
+func (w *World) preSolve(c *Collision) {
+	if c.passingThrough || passesThroughOneWay(c.a, c.b, c.normal) || passesThroughOneWay(c.b, c.a, c.normal.ScaleMult(-one)) {
+		// Once something starts going through a platform, it keeps going until they stop touching
+		c.passingThrough = true
+		c.enabled = false
+	}
+	if w.Listener != nil {
+		w.Listener.PreSolve(c)
+	}
+}
+
+// Resting bodies jitter a little, so only count them as moving away above this speed (m/s)
+const oneWaySlop = 0.01
+
+// True if other should pass through the platform. The normal points from platform to other.
+func passesThroughOneWay(platform, other *Body, normal Vec2) bool {
+	up, ok := platform.OneWay()
+	if !ok {
+		return false
+	}
+
+	// Hitting it from below or from the side
+	if normal.Dot(up) < one/2 {
+		return true
+	}
+
+	// Moving up and away from the platform, like at the top of a jump
+	return other.velocity.Sub(platform.velocity).Dot(up) > fromFloat(oneWaySlop)
+}
+
+func (w *World) postSolve(c *Collision) {
+	if w.Listener != nil && c.enabled {
+		w.Listener.PostSolve(c, c.normalImpulse, c.tangentImpulse)
+	}
+}
+
+// Anything that was touching last tick and isn't anymore has ended
+func (w *World) updateContacts() {
+	for _, c := range w.contacts {
+		if _, ok := w.contactIndex[bodyPair{c.a, c.b}]; !ok && w.Listener != nil {
+			w.Listener.EndContact(c)
+		}
+	}
+
+	// Swap so that we can reuse last tick's slice and map next time
+	w.contacts, w.contactBuffer = w.contactBuffer, w.contacts[:0]
+	w.lastContactIndex, w.contactIndex = w.contactIndex, w.lastContactIndex
+	clear(w.contactIndex)
+}
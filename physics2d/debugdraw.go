@@ -0,0 +1,143 @@
+package physics2d
+
+import "image/color"
+
+// Anything that can draw lines and shapes can show what the engine is doing, without
+// the engine knowing about raylib (or images, or SVG...). Everything is in world space,
+// in meters with y going up, so the backend has to do its own conversion to the screen.
+type DebugDraw interface {
+	// Filled convex polygon
+	DrawPolygon(vertices []Vec2, color color.RGBA)
+	// Filled circle
+	DrawCircle(center Vec2, radius Scalar, color color.RGBA)
+	DrawSegment(a, b Vec2, color color.RGBA)
+	// Size is in pixels, so points stay visible at any zoom
+	DrawPoint(p Vec2, size Scalar, color color.RGBA)
+	// Usually drawn as short x and y axes
+	DrawTransform(xf Transform)
+}
+
+// Backends can implement this too if they want to pick the colors of bodies
+// themselves. Returning false falls back on the default colors.
+type DebugBodyColorer interface {
+	BodyColor(b *Body) (color.RGBA, bool)
+}
+
+type DebugDrawFlags uint32
+
+const (
+	DrawShapes DebugDrawFlags = 1 << iota
+	DrawAABBs
+	DrawContactPoints
+	DrawContactNormals
+	DrawJoints       // There are no joints yet, so this does nothing
+	DrawCenterOfMass // Drawn as the body's transform
+	DrawBroadphase   // Every pair is tested (no broadphase yet), so this does nothing
+
+	DrawAll = DrawShapes | DrawAABBs | DrawContactPoints | DrawContactNormals |
+		DrawJoints | DrawCenterOfMass | DrawBroadphase
+)
+
+// Like everything in image/color, these are alpha-premultiplied. Backends that want
+// straight alpha (raylib, SVG) should convert with color.NRGBAModel.
+var (
+	DebugStaticColor  = color.RGBA{130, 130, 130, 255}
+	DebugOneWayColor  = color.RGBA{255, 161, 0, 255}
+	DebugBallColor    = color.RGBA{253, 249, 0, 255}
+	DebugPolygonColor = color.RGBA{102, 191, 255, 255}
+	DebugSensorColor  = color.RGBA{0, 89, 19, 100} // Raylib's green at 100/255
+	DebugAABBColor    = color.RGBA{200, 122, 255, 255}
+	DebugContactColor = color.RGBA{230, 41, 55, 255}
+)
+
+// How long contact normals are drawn, in meters
+const debugNormalLength = 0.2
+
+// Draws the world the way it was alpha of the way through the last step (see Stepper.Alpha).
+// Pass 1 to draw it as it is now.
+func (w *World) DebugDraw(d DebugDraw, flags DebugDrawFlags, alpha Scalar) {
+	colorer, _ := d.(DebugBodyColorer)
+
+	for _, b := range w.Bodies {
+		position := b.InterpolatedPosition(alpha)
+		xf := NewTransform(position, b.InterpolatedRotation(alpha))
+
+		if flags&DrawShapes != 0 {
+			c := DebugBodyColor(b)
+			if colorer != nil {
+				if custom, ok := colorer.BodyColor(b); ok {
+					c = custom
+				}
+			}
+			switch b.Shape() {
+			case Ball:
+				d.DrawCircle(position, b.Radius(), c)
+			case Polygon:
+				d.DrawPolygon(b.InterpolatedVertices(alpha), c)
+			case PointMass:
+				d.DrawPoint(position, fromInt(4), c)
+			default:
+				d.DrawPolygon(SupportOutline(b.geometry, xf), c)
+			}
+		}
+
+		if flags&DrawAABBs != 0 {
+			min, max := b.geometry.AABB(xf)
+			topLeft := NewVec2(min.x, max.y)
+			bottomRight := NewVec2(max.x, min.y)
+			d.DrawSegment(min, topLeft, DebugAABBColor)
+			d.DrawSegment(topLeft, max, DebugAABBColor)
+			d.DrawSegment(max, bottomRight, DebugAABBColor)
+			d.DrawSegment(bottomRight, min, DebugAABBColor)
+		}
+
+		if flags&DrawCenterOfMass != 0 {
+			d.DrawTransform(xf)
+		}
+	}
+
+	// Contacts are only known at the end of a step, so they aren't interpolated
+	if flags&(DrawContactPoints|DrawContactNormals) != 0 {
+		for _, c := range w.CollisionEvents {
+			for _, cp := range c.ContactPoints() {
+				if flags&DrawContactPoints != 0 {
+					d.DrawPoint(cp, fromInt(6), DebugContactColor)
+				}
+				if flags&DrawContactNormals != 0 {
+					d.DrawSegment(cp, cp.Add(c.normal.ScaleMult(fromFloat(debugNormalLength))), DebugContactColor)
+				}
+			}
+		}
+	}
+}
+
+// The color a body gets when the backend doesn't pick one
+func DebugBodyColor(b *Body) color.RGBA {
+	switch {
+	case b.sensor:
+		return DebugSensorColor
+	case b.oneWayNormal != Vec2{}:
+		return DebugOneWayColor
+	case b.inverseMass == 0:
+		return DebugStaticColor
+	case b.Shape() == Ball:
+		return DebugBallColor
+	}
+	return DebugPolygonColor
+}
+
+// Custom shapes only tell us their support points, so trace the outline with those.
+// Backends that don't go through DebugDraw can use it to draw them too.
+func SupportOutline(shape Shape, xf Transform) []Vec2 {
+	const numPoints = 32
+	outline := make([]Vec2, 0, numPoints)
+	for i := range numPoints {
+		// Clockwise, like the rest of the engine's polygons
+		angle := mul(-2*pi, fromInt(i)) / numPoints
+		p := shape.Support(xf, NewVec2(cos(angle), sin(angle)))
+		if len(outline) == 0 || p != outline[len(outline)-1] {
+			outline = append(outline, p)
+		}
+	}
+	return outline
+}
@@ -0,0 +1,136 @@
+package physics2d
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+)
+
+// Deterministic mode
+//
+// The same World with the same inputs will always end up in bit-identical states, as long as:
+//
+//   - World.Deterministic is set. Bodies are then always stepped in the order they were added
+//     (by ID), even if game code shuffles World.Bodies around, so every pair of bodies is
+//     tested and resolved in the same order every run. This sorts World.Bodies in place, so
+//     anything kept in a parallel slice should be looked up by ID or *Body instead. Scenes
+//     are sorted when they're loaded, and appending new bodies keeps them sorted.
+//   - UpdatePhysics gets the same dt every time. Use a Stepper, never the raw frame time.
+//   - Inputs (AddBody, DeleteBody, forces, impulses, pausing) happen between the same ticks.
+//   - Callbacks like ShouldCollide, Forces and the ContactListener are deterministic too.
+//   - Every machine runs the same binary, or at least one built for the same GOARCH and
+//     microarchitecture level (like GOAMD64). The spec lets the compiler fuse x*y + z into one
+//     instruction that rounds once instead of twice, and whether it does depends on the target
+//     (arm64 always can, amd64 can from GOAMD64=v3), which changes the last bit of some results.
+//
+// The engine itself never iterates over maps or depends on pointer addresses, and contact
+// events come out in the order that the pairs were found.
+//
+// StateHash summarizes the state so that replays and lockstep peers can check they haven't
+// drifted apart (desynced) without sending the whole world around. When they have,
+// Snapshot and Restore can rewind to the last tick everyone agreed on.
+//
+// If the machines can't all run the same binary, build with -tags p2dfixed. Every Scalar is
+// then a fixed.Fixed instead of a float64, and integer math comes out the same everywhere.
+// Only this package and fixed are built for it, the raster, svg and server packages and the
+// tools still use floats. Run its tests with
+//
+//	go test -tags p2dfixed ./physics2d/ ./physics2d/fixed/
+
+// Bodies are sorted by ID, which is the order they were added in
+func (w *World) sortBodies() {
+	slices.SortStableFunc(w.Bodies, func(a, b *Body) int {
+		if a.id < b.id {
+			return -1
+		}
+		if a.id > b.id {
+			return 1
+		}
+		return 0
+	})
+}
+
+// A 64 bit FNV-1a hash of everything that affects how the world steps: the world's settings,
+// every body (including its shape), which pairs were touching last tick and which one-way
+// platforms they were passing through, and the sensor overlaps. Two worlds with the same hash
+// are (almost certainly) in bit-identical states.
+//
+// Custom shapes only add their type, since there's no way to look inside them. Callbacks
+// (Listener, ShouldCollide, Forces and Integrator) can't be hashed either.
+func (w *World) StateHash() uint64 {
+	w.assignIDs()
+	h := fnv.New64a()
+	buf := make([]byte, 0, 256)
+
+	putFloat := func(f Scalar) {
+		buf = binary.LittleEndian.AppendUint64(buf, scalarBits(f))
+	}
+	putVec := func(v Vec2) {
+		putFloat(v.x)
+		putFloat(v.y)
+	}
+
+	putBool := func(b bool) {
+		if b {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+
+	putVec(w.dimensions)
+	putFloat(w.gravity)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(w.timeSteps))
+	putBool(w.Paused)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(w.Bodies)))
+	h.Write(buf)
+
+	for _, b := range w.Bodies {
+		buf = buf[:0]
+		buf = binary.LittleEndian.AppendUint64(buf, b.id)
+		buf = append(buf, byte(b.Shape()))
+		putVec(b.position)
+		putVec(b.velocity)
+		putVec(b.acceleration)
+		putFloat(b.rotation)
+		putFloat(b.rotationalVelocity)
+		putFloat(b.rotationalAcceleration)
+		putFloat(b.inverseMass)
+		putFloat(b.inverseMomentOfIntertia)
+		putFloat(b.restitution)
+		putFloat(b.staticFriction)
+		putFloat(b.dynamicFriction)
+		putFloat(b.surfaceSpeed)
+		putVec(b.oneWayNormal)
+		buf = binary.LittleEndian.AppendUint16(buf, b.filter.Category)
+		buf = binary.LittleEndian.AppendUint16(buf, b.filter.Mask)
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(b.filter.Group))
+		putBool(b.sensor)
+		switch geometry := b.geometry.(type) {
+		case *Circle:
+			putFloat(geometry.Radius)
+		case *ConvexPolygon:
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(len(geometry.vertices)))
+			for _, v := range geometry.vertices {
+				putVec(v)
+			}
+		}
+		h.Write(buf)
+	}
+
+	// Contacts from last tick decide who keeps passing through one-way platforms
+	buf = buf[:0]
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(w.contacts)))
+	for _, c := range w.contacts {
+		buf = binary.LittleEndian.AppendUint64(buf, c.a.id)
+		buf = binary.LittleEndian.AppendUint64(buf, c.b.id)
+		putBool(c.passingThrough)
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(w.sensorOverlaps)))
+	for _, pair := range w.sensorOverlaps {
+		buf = binary.LittleEndian.AppendUint64(buf, pair.a.id)
+		buf = binary.LittleEndian.AppendUint64(buf, pair.b.id)
+	}
+	h.Write(buf)
+	return h.Sum64()
+}
@@ -0,0 +1,29 @@
+package physics2d
+
+// Collision filtering works like Box2D. Every body belongs to one or more
+// categories and has a mask of the categories it is willing to collide with.
+// Two bodies only collide if each one's category is in the other's mask.
+//
+// Groups override the category bits. Bodies that share a positive group always
+// collide, and bodies that share a negative group never do. Group 0 means no group.
+type Filter struct {
+	Category uint16
+	Mask     uint16
+	Group    int16
+}
+
+// Everything collides with everything by default
+func DefaultFilter() Filter {
+	return Filter{
+		Category: 0x0001,
+		Mask:     0xFFFF,
+		Group:    0,
+	}
+}
+
+func (f1 Filter) CollidesWith(f2 Filter) bool {
+	if f1.Group != 0 && f1.Group == f2.Group {
+		return f1.Group > 0
+	}
+	return f1.Mask&f2.Category != 0 && f2.Mask&f1.Category != 0
+}
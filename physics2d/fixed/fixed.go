@@ -0,0 +1,225 @@
+// Package fixed is the fixed-point number that physics2d runs on when it's built with
+// -tags p2dfixed, for when every machine has to get exactly the same answer (lockstep
+// multiplayer). Floats can round differently between CPUs and compilers, but integer
+// math can't.
+package fixed
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Q32.32 fixed-point number: 32 bits of integer and 32 bits of fraction.
+// Add, subtract and compare with the normal operators, but use Mul and Div.
+//
+// Values go up to about ±2.1e9. Mul, Div and Abs saturate at MaxFixed and MinFixed
+// instead of overflowing, but + and - wrap around like any int64 would and flip the
+// sign. Nothing in the engine gets near that as long as positions, velocities and
+// impulses stay well under a million, which is a long way outside any sensible world.
+type Fixed int64
+
+const (
+	fractionBits = 32
+
+	One    Fixed = 1 << fractionBits
+	Half   Fixed = One / 2
+	Pi     Fixed = 13493037705 // Rounded from pi * 2^32
+	TwoPi  Fixed = 26986075409
+	HalfPi Fixed = 6746518852
+
+	quarterPi   Fixed = 3373259426
+	tanEighthPi Fixed = 1779033704 // tan(pi/8), where the atan series starts to get slow
+
+	MaxFixed Fixed = math.MaxInt64
+	MinFixed Fixed = math.MinInt64
+)
+
+func FromInt(i int) Fixed {
+	return Fixed(i) << fractionBits
+}
+
+// Only use this to set things up. Converting is exact for the same input on every
+// machine, but the floats you got the input from might not be.
+func FromFloat(f float64) Fixed {
+	return Fixed(math.Round(f * float64(One)))
+}
+
+// For drawing and debugging, never feed this back into the simulation
+func (a Fixed) Float() float64 {
+	return float64(a) / float64(One)
+}
+
+func (a Fixed) Abs() Fixed {
+	if a == MinFixed {
+		return MaxFixed
+	}
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func Min(a, b Fixed) Fixed {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func Max(a, b Fixed) Fixed {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// The full product needs 128 bits, then we shift the extra fraction back off (rounding).
+// Saturates if the result doesn't fit.
+func (a Fixed) Mul(b Fixed) Fixed {
+	negative := (a < 0) != (b < 0)
+	hi, lo := bits.Mul64(uint64(a.Abs()), uint64(b.Abs()))
+	result := hi<<(64-fractionBits) | lo>>fractionBits
+	if lo&(1<<(fractionBits-1)) != 0 {
+		result++
+	}
+	if hi>>(64-fractionBits) != 0 || result > uint64(MaxFixed) {
+		if negative {
+			return MinFixed
+		}
+		return MaxFixed
+	}
+	if negative {
+		return -Fixed(result)
+	}
+	return Fixed(result)
+}
+
+// Saturates instead of panicking when dividing by zero or overflowing
+func (a Fixed) Div(b Fixed) Fixed {
+	negative := (a < 0) != (b < 0)
+	ua := uint64(a.Abs())
+	ub := uint64(b.Abs())
+
+	// a << 32 as a 128 bit number
+	hi := ua >> (64 - fractionBits)
+	lo := ua << fractionBits
+	if ub == 0 || hi >= ub {
+		if negative {
+			return MinFixed
+		}
+		return MaxFixed
+	}
+	result, _ := bits.Div64(hi, lo, ub)
+	if negative {
+		return -Fixed(result)
+	}
+	return Fixed(result)
+}
+
+// Newton's method on the 128 bit integer a << 32, starting above the answer so it only goes down
+func (a Fixed) Sqrt() Fixed {
+	if a <= 0 {
+		return 0
+	}
+	hi := uint64(a) >> (64 - fractionBits)
+	lo := uint64(a) << fractionBits
+
+	bitLength := 64 + bits.Len64(hi)
+	if hi == 0 {
+		bitLength = bits.Len64(lo)
+	}
+	root := uint64(1) << ((bitLength + 1) / 2)
+	for {
+		// root is always >= the real square root, so hi < root and Div64 can't overflow
+		quotient, _ := bits.Div64(hi, lo, root)
+		next := (root + quotient) / 2
+		if next >= root {
+			return Fixed(root)
+		}
+		root = next
+	}
+}
+
+// Wraps the angle into [-pi, pi]
+func wrapAngle(a Fixed) Fixed {
+	a %= TwoPi
+	if a > Pi {
+		a -= TwoPi
+	} else if a < -Pi {
+		a += TwoPi
+	}
+	return a
+}
+
+// Taylor series after folding the angle into [-pi/2, pi/2], good to about 1e-7
+func Sin(a Fixed) Fixed {
+	x := wrapAngle(a)
+	if x > HalfPi {
+		x = Pi - x
+	} else if x < -HalfPi {
+		x = -Pi - x
+	}
+
+	// x(1 - x²/6(1 - x²/20(1 - x²/42(1 - x²/72(1 - x²/110)))))
+	x2 := x.Mul(x)
+	result := One - x2/110
+	result = One - x2.Mul(result)/72
+	result = One - x2.Mul(result)/42
+	result = One - x2.Mul(result)/20
+	result = One - x2.Mul(result)/6
+	return x.Mul(result)
+}
+
+func Cos(a Fixed) Fixed {
+	return Sin(wrapAngle(a) + HalfPi)
+}
+
+// Angle of the point (x, y) from the x axis, in [-pi, pi] like math.Atan2. Good to about 1e-9.
+func Atan2(y, x Fixed) Fixed {
+	if x == 0 && y == 0 {
+		return 0
+	}
+
+	// Fold everything into the first octant, where the ratio is between 0 and 1
+	ax, ay := x.Abs(), y.Abs()
+	swapped := ay > ax
+	var ratio Fixed
+	if swapped {
+		ratio = ax.Div(ay)
+	} else {
+		ratio = ay.Div(ax)
+	}
+
+	// atan(r) = pi/4 + atan((r-1)/(r+1)) gets the ratio under tan(pi/8)
+	angle := Fixed(0)
+	if ratio > tanEighthPi {
+		ratio = (ratio - One).Div(ratio + One)
+		angle = quarterPi
+	}
+	angle += atanSeries(ratio)
+
+	if swapped {
+		angle = HalfPi - angle
+	}
+	if x < 0 {
+		angle = Pi - angle
+	}
+	if y < 0 {
+		angle = -angle
+	}
+	return angle
+}
+
+// z - z³/3 + z⁵/5 - ... up to z²¹, which is plenty for |z| <= tan(pi/8)
+func atanSeries(z Fixed) Fixed {
+	z2 := z.Mul(z)
+	result := Fixed(0)
+	for n := 21; n >= 1; n -= 2 {
+		term := One / Fixed(n)
+		if n%4 == 3 {
+			term = -term
+		}
+		result = term + z2.Mul(result)
+	}
+	return z.Mul(result)
+}
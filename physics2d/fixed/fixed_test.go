@@ -0,0 +1,45 @@
+package fixed
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMulSaturates(t *testing.T) {
+	big := FromInt(1 << 20)
+	cases := []struct {
+		a, b, want Fixed
+	}{
+		{big, big, MaxFixed},
+		{-big, big, MinFixed},
+		{big, -big, MinFixed},
+		{-big, -big, MaxFixed},
+		{MaxFixed, 2 * One, MaxFixed},
+		{FromInt(3), FromInt(-4), FromInt(-12)},
+		{Half, Half, One / 4},
+	}
+	for _, c := range cases {
+		if got := c.a.Mul(c.b); got != c.want {
+			t.Errorf("%v * %v = %v, want %v", c.a.Float(), c.b.Float(), got.Float(), c.want.Float())
+		}
+	}
+}
+
+func TestTrigMatchesFloats(t *testing.T) {
+	for i := -40; i <= 40; i++ {
+		angle := float64(i) * 0.17
+		if got := Sin(FromFloat(angle)).Float(); math.Abs(got-math.Sin(angle)) > 1e-6 {
+			t.Errorf("Sin(%v) = %v, want %v", angle, got, math.Sin(angle))
+		}
+		if got := Cos(FromFloat(angle)).Float(); math.Abs(got-math.Cos(angle)) > 1e-6 {
+			t.Errorf("Cos(%v) = %v, want %v", angle, got, math.Cos(angle))
+		}
+	}
+	for _, x := range []float64{-3, -1, -0.4, 0, 0.25, 1, 7} {
+		for _, y := range []float64{-5, -1, -0.3, 0, 0.6, 1, 2} {
+			if got := Atan2(FromFloat(y), FromFloat(x)).Float(); math.Abs(got-math.Atan2(y, x)) > 1e-8 {
+				t.Errorf("Atan2(%v, %v) = %v, want %v", y, x, got, math.Atan2(y, x))
+			}
+		}
+	}
+}
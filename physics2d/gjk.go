@@ -0,0 +1,279 @@
+package physics2d
+
+import "slices"
+
+// GJK and EPA work on anything convex, as long as it can tell us which of its
+// points is furthest in a given direction. They don't care about the shapes at
+// all, which makes them slower than the SAT tests in collision.go but way more general.
+
+// Anything convex that can find its furthest point in a direction
+type Supporter interface {
+	Support(direction Vec2) Vec2
+}
+
+// Lets a plain function be used as a Supporter
+type SupportFunc func(direction Vec2) Vec2
+
+func (f SupportFunc) Support(direction Vec2) Vec2 {
+	return f(direction)
+}
+
+type DistanceResult struct {
+	Distance    Scalar
+	PointA      Vec2 // Closest point on a
+	PointB      Vec2 // Closest point on b
+	Overlapping bool // The shapes touch or overlap, so the distance is 0 and the points mean nothing
+}
+
+type PenetrationResult struct {
+	Depth  Scalar
+	Normal Vec2 // Normalized and in the a->b direction, like a Collision
+	PointA Vec2 // Deepest point of a inside b
+	PointB Vec2 // Deepest point of b inside a
+}
+
+// GJK gives up after this many iterations. Polygons usually take less than 10.
+const gjkMaxIterations = 32
+
+// EPA stops once the polytope grows by less than this
+const epaTolerance = 1e-9
+
+// One vertex of the Minkowski difference a-b, and the points of a and b that made it
+type simplexVertex struct {
+	a, b, w Vec2
+}
+
+func minkowskiSupport(a, b Supporter, direction Vec2) simplexVertex {
+	pa := a.Support(direction)
+	pb := b.Support(direction.ScaleMult(-one))
+	return simplexVertex{pa, pb, pa.Sub(pb)}
+}
+
+// Finds the distance and closest points between two convex shapes using GJK
+func GJKDistance(a, b Supporter) DistanceResult {
+	simplex, weights, overlapping := gjk(a, b)
+	if overlapping {
+		return DistanceResult{Overlapping: true}
+	}
+
+	var pointA, pointB Vec2
+	for i, v := range simplex {
+		pointA = pointA.Add(v.a.ScaleMult(weights[i]))
+		pointB = pointB.Add(v.b.ScaleMult(weights[i]))
+	}
+	return DistanceResult{
+		Distance: pointA.Distance(pointB),
+		PointA:   pointA,
+		PointB:   pointB,
+	}
+}
+
+// Finds how far two overlapping convex shapes are overlapping using GJK and then EPA.
+// Returns false if they aren't overlapping. Shapes that are only just touching have a depth of 0.
+func EPAPenetration(a, b Supporter) (PenetrationResult, bool) {
+	simplex, _, overlapping := gjk(a, b)
+	if !overlapping {
+		return PenetrationResult{}, false
+	}
+	polytope, ok := blowUpSimplex(a, b, simplex)
+	if !ok {
+		return PenetrationResult{}, false
+	}
+	return epa(a, b, polytope), true
+}
+
+// The heart of GJK. Keeps a simplex (point, segment or triangle) of the Minkowski difference
+// and moves it towards the origin until it can't get any closer. Returns the simplex, the
+// weights of its vertices that give the point closest to the origin, and whether the origin
+// is inside (so the shapes overlap).
+func gjk(a, b Supporter) ([]simplexVertex, []Scalar, bool) {
+	simplex := make([]simplexVertex, 1, 3)
+	simplex[0] = minkowskiSupport(a, b, NewVec2(one, 0))
+	weights := []Scalar{one}
+
+	for range gjkMaxIterations {
+		switch len(simplex) {
+		case 2:
+			simplex, weights = solveSegment(simplex)
+		case 3:
+			simplex, weights = solveTriangle(simplex)
+		}
+		if len(simplex) == 3 {
+			// The origin is inside the triangle
+			return simplex, weights, true
+		}
+
+		closest := ZeroVec2()
+		for i, v := range simplex {
+			closest = closest.Add(v.w.ScaleMult(weights[i]))
+		}
+		closestSquared := closest.LengthSquared()
+		if closestSquared < fromFloat(1e-18) {
+			// The origin is on the simplex, so they're touching
+			return simplex, weights, true
+		}
+
+		next := minkowskiSupport(a, b, closest.ScaleMult(-one))
+
+		// Stop if we can't get any closer to the origin
+		if closestSquared-closest.Dot(next.w) <= mul(fromFloat(1e-12), closestSquared) {
+			break
+		}
+		duplicate := false
+		for _, v := range simplex {
+			if v.w == next.w {
+				duplicate = true
+			}
+		}
+		if duplicate {
+			break
+		}
+
+		simplex = append(simplex, next)
+		weights = append(weights, 0)
+	}
+	return simplex, weights, false
+}
+
+// Closest point on the segment to the origin. Drops whichever vertex isn't needed.
+func solveSegment(s []simplexVertex) ([]simplexVertex, []Scalar) {
+	w1, w2 := s[0].w, s[1].w
+	e12 := w2.Sub(w1)
+
+	d12_2 := -w1.Dot(e12)
+	if d12_2 <= 0 {
+		return s[:1], []Scalar{one}
+	}
+	d12_1 := w2.Dot(e12)
+	if d12_1 <= 0 {
+		return []simplexVertex{s[1]}, []Scalar{one}
+	}
+	sum := d12_1 + d12_2
+	return s, []Scalar{div(d12_1, sum), div(d12_2, sum)}
+}
+
+// Closest point on the triangle to the origin, using the barycentric coordinates
+// of each vertex and edge region. Keeps all 3 vertices only if the origin is inside.
+func solveTriangle(s []simplexVertex) ([]simplexVertex, []Scalar) {
+	w1, w2, w3 := s[0].w, s[1].w, s[2].w
+
+	e12 := w2.Sub(w1)
+	d12_1 := w2.Dot(e12)
+	d12_2 := -w1.Dot(e12)
+
+	e13 := w3.Sub(w1)
+	d13_1 := w3.Dot(e13)
+	d13_2 := -w1.Dot(e13)
+
+	e23 := w3.Sub(w2)
+	d23_1 := w3.Dot(e23)
+	d23_2 := -w2.Dot(e23)
+
+	n123 := e12.Cross(e13)
+	d123_1 := mul(n123, w2.Cross(w3))
+	d123_2 := mul(n123, w3.Cross(w1))
+	d123_3 := mul(n123, w1.Cross(w2))
+
+	switch {
+	case d12_2 <= 0 && d13_2 <= 0:
+		return []simplexVertex{s[0]}, []Scalar{one}
+	case d12_1 > 0 && d12_2 > 0 && d123_3 <= 0:
+		sum := d12_1 + d12_2
+		return []simplexVertex{s[0], s[1]}, []Scalar{div(d12_1, sum), div(d12_2, sum)}
+	case d13_1 > 0 && d13_2 > 0 && d123_2 <= 0:
+		sum := d13_1 + d13_2
+		return []simplexVertex{s[0], s[2]}, []Scalar{div(d13_1, sum), div(d13_2, sum)}
+	case d12_1 <= 0 && d23_2 <= 0:
+		return []simplexVertex{s[1]}, []Scalar{one}
+	case d13_1 <= 0 && d23_1 <= 0:
+		return []simplexVertex{s[2]}, []Scalar{one}
+	case d23_1 > 0 && d23_2 > 0 && d123_1 <= 0:
+		sum := d23_1 + d23_2
+		return []simplexVertex{s[1], s[2]}, []Scalar{div(d23_1, sum), div(d23_2, sum)}
+	}
+	sum := d123_1 + d123_2 + d123_3
+	return s, []Scalar{div(d123_1, sum), div(d123_2, sum), div(d123_3, sum)}
+}
+
+// EPA needs a triangle to start from, but GJK stops early when the shapes are only
+// touching. Grow the simplex back into a triangle if we can.
+func blowUpSimplex(a, b Supporter, simplex []simplexVertex) ([]simplexVertex, bool) {
+	polytope := slices.Clone(simplex)
+	if len(polytope) == 1 {
+		for _, d := range []Vec2{{one, 0}, {-one, 0}, {0, one}, {0, -one}} {
+			v := minkowskiSupport(a, b, d)
+			if !v.w.CloseTo(polytope[0].w) {
+				polytope = append(polytope, v)
+				break
+			}
+		}
+	}
+	if len(polytope) == 2 {
+		perp := polytope[1].w.Sub(polytope[0].w).Perpendicular()
+		for _, d := range []Vec2{perp, perp.ScaleMult(-one)} {
+			v := minkowskiSupport(a, b, d)
+			if abs(v.w.Sub(polytope[0].w).Dot(perp)) > fromFloat(1e-12) {
+				polytope = append(polytope, v)
+				break
+			}
+		}
+	}
+	if len(polytope) < 3 {
+		// Flat shapes, there's no area to work with
+		return nil, false
+	}
+
+	// EPA expects counter-clockwise winding
+	if polytope[1].w.Sub(polytope[0].w).Cross(polytope[2].w.Sub(polytope[0].w)) < 0 {
+		polytope[1], polytope[2] = polytope[2], polytope[1]
+	}
+	return polytope, true
+}
+
+// Pushes the edge of the polytope that's closest to the origin outwards until it lands
+// on the edge of the Minkowski difference. That edge's distance is the penetration depth.
+func epa(a, b Supporter, polytope []simplexVertex) PenetrationResult {
+	var normal Vec2
+	var depth Scalar
+	var edge int
+	for range gjkMaxIterations * 2 {
+		depth = maxScalar
+		for i := range len(polytope) {
+			vCurr := polytope[i].w
+			vNext := polytope[(i+1)%len(polytope)].w
+			e := vNext.Sub(vCurr)
+			if e.LengthSquared() == 0 {
+				continue
+			}
+			// Outwards for a counter-clockwise polytope
+			n := NewVec2(e.y, -e.x).Normalize()
+			d := n.Dot(vCurr)
+			if d < depth {
+				depth = d
+				normal = n
+				edge = i
+			}
+		}
+
+		next := minkowskiSupport(a, b, normal)
+		if next.w.Dot(normal)-depth < fromFloat(epaTolerance) {
+			break
+		}
+		polytope = slices.Insert(polytope, edge+1, next)
+	}
+
+	// Find the deepest points the same way GJK finds closest points, on the closest edge
+	edgeSimplex, weights := solveSegment([]simplexVertex{polytope[edge], polytope[(edge+1)%len(polytope)]})
+	var pointA, pointB Vec2
+	for i, v := range edgeSimplex {
+		pointA = pointA.Add(v.a.ScaleMult(weights[i]))
+		pointB = pointB.Add(v.b.ScaleMult(weights[i]))
+	}
+
+	return PenetrationResult{
+		Depth:  depth,
+		Normal: normal,
+		PointA: pointA,
+		PointB: pointB,
+	}
+}
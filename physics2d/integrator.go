@@ -0,0 +1,94 @@
+package physics2d
+
+// Gives the acceleration a body would have at some position and velocity during a step
+type AccelerationFunc func(position, velocity Vec2) Vec2
+
+// Forces that depend on where a body is and how fast it's going, like springs, drag or
+// planets pulling on each other. Returns the force in Newtons.
+type ForceFunc func(b *Body, position, velocity Vec2) Vec2
+
+// Moves a body's position and velocity forward by dt. Constant forces like gravity are
+// already baked into accel, so brute force substepping isn't the only way to get accuracy.
+type Integrator interface {
+	Step(position, velocity Vec2, dt Scalar, accel AccelerationFunc) (Vec2, Vec2)
+}
+
+// Velocity first, then position. Cheap and stable, which is why it's the default,
+// but energy drifts a little every step.
+type SemiImplicitEuler struct{}
+
+func (SemiImplicitEuler) Step(position, velocity Vec2, dt Scalar, accel AccelerationFunc) (Vec2, Vec2) {
+	velocity = velocity.Add(accel(position, velocity).ScaleMult(dt))
+	position = position.Add(velocity.ScaleMult(dt))
+	return position, velocity
+}
+
+// Second order, and keeps energy steady for things like orbits and springs.
+// Velocity dependent forces (drag) use a first order guess of the new velocity.
+type VelocityVerlet struct{}
+
+func (VelocityVerlet) Step(position, velocity Vec2, dt Scalar, accel AccelerationFunc) (Vec2, Vec2) {
+	a0 := accel(position, velocity)
+	position = position.Add(velocity.ScaleMult(dt)).Add(a0.ScaleMult(mul(dt, dt) / 2))
+	a1 := accel(position, velocity.Add(a0.ScaleMult(dt)))
+	velocity = velocity.Add(a0.Add(a1).ScaleMult(dt / 2))
+	return position, velocity
+}
+
+// Classic fourth order Runge-Kutta. Very accurate, but evaluates the forces 4 times per step.
+type RK4 struct{}
+
+func (RK4) Step(position, velocity Vec2, dt Scalar, accel AccelerationFunc) (Vec2, Vec2) {
+	k1x := velocity
+	k1v := accel(position, velocity)
+
+	k2x := velocity.Add(k1v.ScaleMult(dt / 2))
+	k2v := accel(position.Add(k1x.ScaleMult(dt/2)), k2x)
+
+	k3x := velocity.Add(k2v.ScaleMult(dt / 2))
+	k3v := accel(position.Add(k2x.ScaleMult(dt/2)), k3x)
+
+	k4x := velocity.Add(k3v.ScaleMult(dt))
+	k4v := accel(position.Add(k3x.ScaleMult(dt)), k4x)
+
+	position = position.Add(k1x.Add(k2x.ScaleMult(fromInt(2))).Add(k3x.ScaleMult(fromInt(2))).Add(k4x).ScaleMult(dt / 6))
+	velocity = velocity.Add(k1v.Add(k2v.ScaleMult(fromInt(2))).Add(k3v.ScaleMult(fromInt(2))).Add(k4v).ScaleMult(dt / 6))
+	return position, velocity
+}
+
+// Integrates one body with the world's integrator and force fields
+func (w *World) integrate(b *Body, dt Scalar) {
+	if b.inverseMass == 0 {
+		return
+	}
+
+	// Applied forces and gravity don't change during the step
+	constant := b.acceleration.Add(NewVec2(0, -w.gravity))
+	accel := func(position, velocity Vec2) Vec2 {
+		a := constant
+		for _, force := range w.Forces {
+			a = a.Add(force(b, position, velocity).ScaleMult(b.inverseMass))
+		}
+		return a
+	}
+
+	integrator := w.Integrator
+	if integrator == nil {
+		integrator = SemiImplicitEuler{}
+	}
+	b.integrate(integrator, dt, accel)
+}
+
+func (b *Body) integrate(integrator Integrator, dt Scalar, accel AccelerationFunc) {
+	position, velocity := integrator.Step(b.position, b.velocity, dt, accel)
+	b.velocity = velocity
+	b.MoveTo(position)
+
+	// Torques are always constant over a step, so semi-implicit Euler is plenty
+	b.rotationalVelocity += mul(b.rotationalAcceleration, dt)
+	b.Rotate(mul(b.rotationalVelocity, dt))
+
+	// Acceleration is reevaluated every tick
+	b.acceleration = ZeroVec2()
+	b.rotationalAcceleration = 0
+}
@@ -0,0 +1,64 @@
+package physics2d
+
+// These queries include sensors, so use IsSensor if you need to skip them
+
+// Finds every body that contains the point
+func (w *World) QueryPoint(p Vec2) []*Body {
+	var bodies []*Body
+	for _, b := range w.Bodies {
+		min, max := b.AABB()
+		if !aabbsOverlap(p, p, min, max) {
+			continue
+		}
+		if containsPoint(b, p) {
+			bodies = append(bodies, b)
+		}
+	}
+	return bodies
+}
+
+// Finds every body whose bounding box overlaps the box from min to max
+func (w *World) QueryAABB(min, max Vec2) []*Body {
+	var bodies []*Body
+	for _, b := range w.Bodies {
+		bMin, bMax := b.AABB()
+		if aabbsOverlap(min, max, bMin, bMax) {
+			bodies = append(bodies, b)
+		}
+	}
+	return bodies
+}
+
+// Finds every body that the shape would overlap at the transform. Point shapes
+// never overlap anything, so use QueryPoint for those.
+func (w *World) Overlap(shape Shape, xf Transform) []*Body {
+	placed, err := NewBody(shape, xf.Pos, xf.Angle(), 0, 0)
+	if err != nil {
+		return nil
+	}
+	// Exactly the transform we were given, instead of one rebuilt from the angle
+	placed.setTransform(xf)
+	min, max := placed.AABB()
+
+	var bodies []*Body
+	for _, b := range w.Bodies {
+		bMin, bMax := b.AABB()
+		if !aabbsOverlap(min, max, bMin, bMax) {
+			continue
+		}
+		if c, err := Collide(placed, b); err == nil && c != nil {
+			bodies = append(bodies, b)
+		}
+	}
+	return bodies
+}
+
+// A point is just a shape with no size, so GJK can tell us if it's inside
+func containsPoint(b *Body, p Vec2) bool {
+	point := SupportFunc(func(Vec2) Vec2 { return p })
+	return GJKDistance(b, point).Overlapping
+}
+
+func aabbsOverlap(min1, max1, min2, max2 Vec2) bool {
+	return min1.x <= max2.x && max1.x >= min2.x && min1.y <= max2.y && max1.y >= min2.y
+}
@@ -0,0 +1,84 @@
+package raster
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func WritePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Writes numbered frames (frame_00000.png, frame_00001.png...) into a directory,
+// which tools like ffmpeg can turn into a video
+type PNGSequence struct {
+	Dir   string
+	frame int
+}
+
+func NewPNGSequence(dir string) (*PNGSequence, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &PNGSequence{Dir: dir}, nil
+}
+
+func (s *PNGSequence) AddFrame(img image.Image) error {
+	path := filepath.Join(s.Dir, fmt.Sprintf("frame_%05d.png", s.frame))
+	s.frame++
+	return WritePNG(path, img)
+}
+
+// Collects frames for an animated GIF. GIFs only have 256 colors, so frames get
+// snapped to the Plan 9 palette, which is fine for the flat colors we draw with.
+type GIF struct {
+	Delay int // Between frames, in 100ths of a second
+	anim  gif.GIF
+}
+
+func NewGIF(delay int) *GIF {
+	return &GIF{Delay: delay}
+}
+
+func (g *GIF) AddFrame(img image.Image) {
+	bounds := img.Bounds()
+	frame := image.NewPaletted(bounds, palette.Plan9)
+	draw.Draw(frame, bounds, img, bounds.Min, draw.Src)
+	g.anim.Image = append(g.anim.Image, frame)
+	g.anim.Delay = append(g.anim.Delay, g.Delay)
+}
+
+func (g *GIF) NumFrames() int {
+	return len(g.anim.Image)
+}
+
+func (g *GIF) Encode(w io.Writer) error {
+	return gif.EncodeAll(w, &g.anim)
+}
+
+func (g *GIF) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := g.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,206 @@
+// Package raster draws a physics2d world into an image using only the standard
+// library, for when there's no window (CI, reports, servers). It draws the same
+// way as the raylib demo: y goes up in the world but down in the image.
+//
+// It's a physics2d.DebugDraw backend, so it can show anything World.DebugDraw can.
+package raster
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+)
+
+var Background = color.RGBA{13, 27, 42, 255}
+
+type Renderer struct {
+	Width, Height  int // px
+	PixelsPerMeter float64
+	Background     color.RGBA
+
+	// Picks the color of each body. Uses physics2d.DebugBodyColor if nil.
+	BodyColor func(b *p2d.Body) color.RGBA
+
+	// What to draw, shapes and contacts by default
+	Flags p2d.DebugDrawFlags
+}
+
+// Sized to fit the world's dimensions
+func NewRenderer(world *p2d.World, pixelsPerMeter float64) *Renderer {
+	dimensions := world.Dimensions()
+	return &Renderer{
+		Width:          int(math.Ceil(dimensions.X() * pixelsPerMeter)),
+		Height:         int(math.Ceil(dimensions.Y() * pixelsPerMeter)),
+		PixelsPerMeter: pixelsPerMeter,
+		Background:     Background,
+		Flags:          p2d.DrawShapes | p2d.DrawContactPoints | p2d.DrawContactNormals,
+	}
+}
+
+// Same as toRLVec in the demo
+func (r *Renderer) ToPixel(v p2d.Vec2) (float64, float64) {
+	worldHeight := float64(r.Height) / r.PixelsPerMeter
+	return v.X() * r.PixelsPerMeter, (worldHeight - v.Y()) * r.PixelsPerMeter
+}
+
+func (r *Renderer) Render(world *p2d.World) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	r.RenderInto(img, world)
+	return img
+}
+
+// Draws over an existing image, so frames can reuse the same memory
+func (r *Renderer) RenderInto(img *image.RGBA, world *p2d.World) {
+	// Replaces whatever was there, even if the background isn't opaque
+	draw.Draw(img, img.Bounds(), image.NewUniform(r.Background), image.Point{}, draw.Src)
+	world.DebugDraw(canvas{r, img}, r.Flags, 1)
+}
+
+// The physics2d.DebugDraw backend
+type canvas struct {
+	r   *Renderer
+	img *image.RGBA
+}
+
+func (c canvas) BodyColor(b *p2d.Body) (color.RGBA, bool) {
+	if c.r.BodyColor == nil {
+		return color.RGBA{}, false
+	}
+	return c.r.BodyColor(b), true
+}
+
+func (c canvas) DrawPolygon(vertices []p2d.Vec2, col color.RGBA) {
+	if len(vertices) < 3 {
+		return
+	}
+	points := make([][2]float64, len(vertices))
+	for i, v := range vertices {
+		points[i][0], points[i][1] = c.r.ToPixel(v)
+	}
+	fillConvexPolygon(c.img, points, col)
+}
+
+func (c canvas) DrawCircle(center p2d.Vec2, radius float64, col color.RGBA) {
+	x, y := c.r.ToPixel(center)
+	fillCircle(c.img, x, y, radius*c.r.PixelsPerMeter, col)
+}
+
+func (c canvas) DrawSegment(a, b p2d.Vec2, col color.RGBA) {
+	x0, y0 := c.r.ToPixel(a)
+	x1, y1 := c.r.ToPixel(b)
+	drawLine(c.img, x0, y0, x1, y1, 2, col)
+}
+
+func (c canvas) DrawPoint(p p2d.Vec2, size float64, col color.RGBA) {
+	x, y := c.r.ToPixel(p)
+	fillCircle(c.img, x, y, size/2, col)
+}
+
+func (c canvas) DrawTransform(xf p2d.Transform) {
+	const axisLength = 0.1 // m
+	c.DrawSegment(xf.Pos, xf.Pos.Add(xf.Rotate(p2d.NewVec2(axisLength, 0))), color.RGBA{230, 41, 55, 255})
+	c.DrawSegment(xf.Pos, xf.Pos.Add(xf.Rotate(p2d.NewVec2(0, axisLength))), color.RGBA{0, 228, 48, 255})
+}
+
+///////////////////////////////////////////////////////////////////////
+
+// Pixels are filled if their center is inside the shape
+
+func fillRect(img *image.RGBA, rect image.Rectangle, c color.RGBA) {
+	rect = rect.Intersect(img.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			blend(img, x, y, c)
+		}
+	}
+}
+
+// Draws c over the pixel. Both are premultiplied, so this is just dst*(1 - a) + c.
+func blend(img *image.RGBA, x, y int, c color.RGBA) {
+	if c.A == 255 {
+		img.SetRGBA(x, y, c)
+		return
+	}
+	dst := img.RGBAAt(x, y)
+	keep := uint32(255 - c.A)
+	over := func(src, dst uint8) uint8 {
+		return src + uint8((uint32(dst)*keep+127)/255)
+	}
+	img.SetRGBA(x, y, color.RGBA{over(c.R, dst.R), over(c.G, dst.G), over(c.B, dst.B), over(c.A, dst.A)})
+}
+
+// Pixel bounds of a float box, clipped to the image
+func pixelBounds(img *image.RGBA, minX, minY, maxX, maxY float64) image.Rectangle {
+	rect := image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1,
+	)
+	return rect.Intersect(img.Bounds())
+}
+
+func fillCircle(img *image.RGBA, cx, cy, radius float64, c color.RGBA) {
+	bounds := pixelBounds(img, cx-radius, cy-radius, cx+radius, cy+radius)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dx := float64(x) + 0.5 - cx
+			dy := float64(y) + 0.5 - cy
+			if dx*dx+dy*dy <= radius*radius {
+				blend(img, x, y, c)
+			}
+		}
+	}
+}
+
+// Works for either winding, since the image flips it anyway
+func fillConvexPolygon(img *image.RGBA, points [][2]float64, c color.RGBA) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+
+	bounds := pixelBounds(img, minX, minY, maxX, maxY)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if insideConvex(points, float64(x)+0.5, float64(y)+0.5) {
+				blend(img, x, y, c)
+			}
+		}
+	}
+}
+
+// Inside if the point is on the same side of every edge
+func insideConvex(points [][2]float64, x, y float64) bool {
+	sign := 0.0
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		cross := (q[0]-p[0])*(y-p[1]) - (q[1]-p[1])*(x-p[0])
+		if cross*sign < 0 {
+			return false
+		}
+		if cross != 0 {
+			sign = cross
+		}
+	}
+	return true
+}
+
+// Stamps squares along the line, which is plenty for thin lines
+func drawLine(img *image.RGBA, x0, y0, x1, y1, width float64, c color.RGBA) {
+	length := math.Hypot(x1-x0, y1-y0)
+	steps := int(math.Ceil(length * 2))
+	half := width / 2
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		x := x0 + (x1-x0)*t
+		y := y0 + (y1-y0)*t
+		fillRect(img, pixelBounds(img, x-half, y-half, x+half-1, y+half-1), c)
+	}
+}
@@ -0,0 +1,31 @@
+package raster
+
+import (
+	"image/color"
+	"testing"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+)
+
+// Sensors are translucent, so they should tint the background instead of punching holes in it
+func TestSensorBlendsOverBackground(t *testing.T) {
+	sensor := p2d.NewBox(p2d.NewVec2(1, 1), p2d.NewVec2(1, 1), 0, 0, 0)
+	sensor.SetSensor(true)
+	world := p2d.NewWorld([]*p2d.Body{sensor}, p2d.NewVec2(2, 2), 9.8, 1)
+
+	r := NewRenderer(&world, 10)
+	img := r.Render(&world)
+	got := img.RGBAAt(10, 10)
+
+	c := p2d.DebugSensorColor
+	keep := 255 - uint32(c.A)
+	want := color.RGBA{
+		c.R + uint8((uint32(Background.R)*keep+127)/255),
+		c.G + uint8((uint32(Background.G)*keep+127)/255),
+		c.B + uint8((uint32(Background.B)*keep+127)/255),
+		255,
+	}
+	if got != want {
+		t.Errorf("sensor pixel is %v, want %v", got, want)
+	}
+}
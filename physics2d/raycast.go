@@ -0,0 +1,192 @@
+package physics2d
+
+import "slices"
+
+type RayHit struct {
+	Body     *Body
+	Point    Vec2
+	Normal   Vec2   // Surface normal where the ray hit, pointing out of the body
+	Fraction Scalar // How far along the ray the hit was, from 0 to 1 of maxDistance
+}
+
+// Return false to stop getting more hits
+type RayCastCallback func(hit RayHit) bool
+
+// Finds the closest body along the ray that collides with the filter. Sensors are ignored,
+// and so are bodies that the ray starts inside of.
+func (w *World) RayCast(origin, direction Vec2, maxDistance Scalar, filter Filter) (RayHit, bool) {
+	var closest RayHit
+	found := false
+	w.eachRayHit(origin, direction, maxDistance, filter, func(hit RayHit) {
+		// Ties go to the body that comes first, like in RayCastAll
+		if !found || hit.Fraction < closest.Fraction {
+			closest = hit
+			found = true
+		}
+	})
+	return closest, found
+}
+
+// Calls the callback with every body the ray hits, closest first. Only bodies
+// that would collide with something using the given filter are tested.
+func (w *World) RayCastAll(origin, direction Vec2, maxDistance Scalar, filter Filter, callback RayCastCallback) {
+	var hits []RayHit
+	w.eachRayHit(origin, direction, maxDistance, filter, func(hit RayHit) {
+		hits = append(hits, hit)
+	})
+
+	slices.SortStableFunc(hits, func(a, b RayHit) int {
+		if a.Fraction < b.Fraction {
+			return -1
+		}
+		if a.Fraction > b.Fraction {
+			return 1
+		}
+		return 0
+	})
+	for _, hit := range hits {
+		if !callback(hit) {
+			return
+		}
+	}
+}
+
+// Every hit in the order of World.Bodies
+func (w *World) eachRayHit(origin, direction Vec2, maxDistance Scalar, filter Filter, f func(hit RayHit)) {
+	if direction.LengthSquared() == 0 || maxDistance <= 0 {
+		return
+	}
+	direction = direction.Normalize()
+	end := origin.Add(direction.ScaleMult(maxDistance))
+
+	for _, b := range w.Bodies {
+		if b.sensor || !filter.CollidesWith(b.filter) {
+			continue
+		}
+
+		// There is no broadphase yet, so at least skip the bodies whose box we miss
+		min, max := b.AABB()
+		if !segmentHitsAABB(origin, end, min, max) {
+			continue
+		}
+
+		if hit, ok := rayCastBody(b, origin, direction, maxDistance); ok {
+			f(hit)
+		}
+	}
+}
+
+// The direction must be normalized
+func rayCastBody(b *Body, origin, direction Vec2, maxDistance Scalar) (RayHit, bool) {
+	distance, normal, ok := b.geometry.RayCast(b.Transform(), origin, direction, maxDistance)
+	if !ok {
+		return RayHit{}, false
+	}
+	return RayHit{
+		Body:     b,
+		Point:    origin.Add(direction.ScaleMult(distance)),
+		Normal:   normal,
+		Fraction: div(distance, maxDistance),
+	}, true
+}
+
+// Solves |origin + t*direction - center| = radius for the smallest t
+func rayCastCircle(center Vec2, radius Scalar, origin, direction Vec2, maxDistance Scalar) (Scalar, Vec2, bool) {
+	m := origin.Sub(center)
+	b := m.Dot(direction)
+	c := m.LengthSquared() - mul(radius, radius)
+
+	// Starting inside, or outside and pointing away
+	if c <= 0 || b > 0 {
+		return 0, Vec2{}, false
+	}
+
+	discriminant := mul(b, b) - c
+	if discriminant < 0 {
+		return 0, Vec2{}, false
+	}
+
+	t := -b - sqrt(discriminant)
+	if t > maxDistance {
+		return 0, Vec2{}, false
+	}
+	normal := origin.Add(direction.ScaleMult(t)).Sub(center).Normalize()
+	return t, normal, true
+}
+
+// Clips the ray against every edge of the polygon (Cyrus-Beck). Only works for convex polygons.
+func rayCastPolygon(vertices []Vec2, center Vec2, origin, direction Vec2, maxDistance Scalar) (Scalar, Vec2, bool) {
+	var lower Scalar
+	upper := maxDistance
+	hitEdge := -1
+	var hitNormal Vec2
+
+	for i := range len(vertices) {
+		vCurr := vertices[i]
+		vNext := vertices[(i+1)%len(vertices)]
+		normal := vNext.Sub(vCurr).Perpendicular()
+
+		// Make sure the normal points out of the polygon, whichever way the vertices wind
+		if normal.Dot(vCurr.Sub(center)) < 0 {
+			normal = normal.ScaleMult(-one)
+		}
+
+		numerator := normal.Dot(vCurr.Sub(origin))
+		denominator := normal.Dot(direction)
+
+		if denominator == 0 {
+			// Parallel to this edge and on the outside of it
+			if numerator < 0 {
+				return 0, Vec2{}, false
+			}
+		} else if denominator < 0 && numerator < mul(lower, denominator) {
+			// Entering through this edge
+			lower = div(numerator, denominator)
+			hitEdge = i
+			hitNormal = normal
+		} else if denominator > 0 && numerator < mul(upper, denominator) {
+			// Leaving through this edge
+			upper = div(numerator, denominator)
+		}
+
+		if upper < lower {
+			return 0, Vec2{}, false
+		}
+	}
+
+	// The ray started inside the polygon
+	if hitEdge < 0 {
+		return 0, Vec2{}, false
+	}
+	return lower, hitNormal.Normalize(), true
+}
+
+// Slab test for the segment from start to end
+func segmentHitsAABB(start, end, boxMin, boxMax Vec2) bool {
+	var tMin Scalar
+	tMax := one
+	d := end.Sub(start)
+
+	for _, axis := range [2]struct{ s, d, min, max Scalar }{
+		{start.x, d.x, boxMin.x, boxMax.x},
+		{start.y, d.y, boxMin.y, boxMax.y},
+	} {
+		if axis.d == 0 {
+			if axis.s < axis.min || axis.s > axis.max {
+				return false
+			}
+			continue
+		}
+		t1 := div(axis.min-axis.s, axis.d)
+		t2 := div(axis.max-axis.s, axis.d)
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = max(tMin, t1)
+		tMax = min(tMax, t2)
+		if tMin > tMax {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,405 @@
+package physics2d
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Recording and replaying
+//
+// A Recorder watches a world and writes down the starting scene (along with what was
+// touching what, which the scene doesn't have) plus everything that
+// happened to it from outside between steps: bodies being added or deleted, forces,
+// impulses, moves, friction changes and pausing. It doesn't need to be told about any of
+// it. Before every step it compares each body to how the last step left it, and anything
+// that changed must have been done by the game.
+//
+// Changes are saved as the values they ended up with instead of the calls that made them,
+// so a Replayer puts back exactly the same bits and the replay can't drift (as long as the
+// rules in determinism.go are followed).
+
+// Bump this whenever the format changes
+const RecordingVersion = 2
+
+type Recording struct {
+	Version int             `json:"version"`
+	Scene   json.RawMessage `json:"scene"`
+
+	// The contact cache and sensor overlaps from the tick before recording started.
+	// They decide who keeps passing through one-way platforms and which events fire.
+	Contacts       []RecordedContact `json:"contacts,omitempty"`
+	SensorOverlaps [][2]uint64       `json:"sensorOverlaps,omitempty"`
+
+	Steps []RecordedStep `json:"steps"`
+}
+
+// A Collision with its bodies saved by ID
+type RecordedContact struct {
+	A               uint64 `json:"a"`
+	B               uint64 `json:"b"`
+	Normal          Vec2   `json:"normal"`
+	Depth           Scalar `json:"depth"`
+	Enabled         bool   `json:"enabled"`
+	Restitution     Scalar `json:"restitution"`
+	StaticFriction  Scalar `json:"staticFriction"`
+	DynamicFriction Scalar `json:"dynamicFriction"`
+	PassingThrough  bool   `json:"passingThrough,omitempty"`
+	ContactPoints   []Vec2 `json:"contactPoints,omitempty"`
+	RelativeSpeed   Scalar `json:"relativeSpeed"`
+	NormalImpulse   Scalar `json:"normalImpulse"`
+	TangentImpulse  Scalar `json:"tangentImpulse"`
+}
+
+func newRecordedContact(c *Collision) RecordedContact {
+	return RecordedContact{
+		c.a.id, c.b.id, c.normal, c.depth,
+		c.enabled, c.restitution, c.staticFriction, c.dynamicFriction,
+		c.passingThrough, slices.Clone(c.contactPoints),
+		c.relativeSpeed, c.normalImpulse, c.tangentImpulse,
+	}
+}
+
+func (rc *RecordedContact) collision(bodies map[uint64]*Body) (*Collision, error) {
+	a, b := bodies[rc.A], bodies[rc.B]
+	if a == nil || b == nil {
+		return nil, fmt.Errorf("physics2d: recorded contact between missing bodies %d and %d", rc.A, rc.B)
+	}
+	return &Collision{
+		a, b, rc.Normal, rc.Depth,
+		rc.Enabled, rc.Restitution, rc.StaticFriction, rc.DynamicFriction,
+		rc.PassingThrough, slices.Clone(rc.ContactPoints),
+		rc.RelativeSpeed, rc.NormalImpulse, rc.TangentImpulse,
+	}, nil
+}
+
+// One or more steps in a row with the same dt. Only the first one has inputs.
+type RecordedStep struct {
+	DT     Scalar  `json:"dt"`
+	Count  int     `json:"count,omitempty"` // 1 if left out
+	Inputs []Input `json:"inputs,omitempty"`
+}
+
+// Something the game did to the world before a step
+type Input struct {
+	Kind   string      `json:"kind"` // "add", "delete", "set" or "pause"
+	ID     uint64      `json:"id,omitempty"`
+	Body   *Body       `json:"body,omitempty"`  // add, as it was when it was added
+	State  *BodyInputs `json:"state,omitempty"` // set
+	Paused bool        `json:"paused,omitempty"`
+}
+
+// Everything about a body that the game can change from outside
+type BodyInputs struct {
+	Position               Vec2   `json:"position"`
+	Velocity               Vec2   `json:"velocity"`
+	Acceleration           Vec2   `json:"acceleration"`
+	Rotation               Scalar `json:"rotation"`
+	RotationalVelocity     Scalar `json:"rotationalVelocity"`
+	RotationalAcceleration Scalar `json:"rotationalAcceleration"`
+	StaticFriction         Scalar `json:"staticFriction"`
+	DynamicFriction        Scalar `json:"dynamicFriction"`
+	SurfaceSpeed           Scalar `json:"surfaceSpeed"`
+	Filter                 Filter `json:"filter"`
+	Sensor                 bool   `json:"sensor"`
+	OneWayNormal           Vec2   `json:"oneWayNormal"`
+}
+
+func newBodyInputs(b *Body) BodyInputs {
+	return BodyInputs{
+		b.position, b.velocity, b.acceleration,
+		b.rotation, b.rotationalVelocity, b.rotationalAcceleration,
+		b.staticFriction, b.dynamicFriction, b.surfaceSpeed,
+		b.filter, b.sensor, b.oneWayNormal,
+	}
+}
+
+func (bi *BodyInputs) apply(b *Body) {
+	b.MoveTo(bi.Position)
+	b.RotateTo(bi.Rotation)
+	b.velocity = bi.Velocity
+	b.acceleration = bi.Acceleration
+	b.rotationalVelocity = bi.RotationalVelocity
+	b.rotationalAcceleration = bi.RotationalAcceleration
+	b.staticFriction = bi.StaticFriction
+	b.dynamicFriction = bi.DynamicFriction
+	b.surfaceSpeed = bi.SurfaceSpeed
+	b.filter = bi.Filter
+	b.sensor = bi.Sensor
+	b.oneWayNormal = bi.OneWayNormal
+}
+
+// Called by UpdatePhysics around every step
+type stepHook interface {
+	beforeStep(w *World, dt Scalar)
+	afterStep(w *World)
+}
+
+///////////////////////////////////////////////////////////////////////
+
+type Recorder struct {
+	world     *World
+	recording Recording
+	last      map[uint64]BodyInputs
+	paused    bool
+}
+
+// Starts recording the world. This turns on World.Deterministic, since a recording
+// isn't much use if it doesn't play back the same way.
+func NewRecorder(w *World) (*Recorder, error) {
+	if w.hook != nil {
+		return nil, errors.New("physics2d: world is already being recorded or replayed")
+	}
+	scene, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	w.Deterministic = true
+	r := &Recorder{
+		world:     w,
+		recording: Recording{Version: RecordingVersion, Scene: scene},
+	}
+	for _, c := range w.contacts {
+		r.recording.Contacts = append(r.recording.Contacts, newRecordedContact(c))
+	}
+	for _, p := range w.sensorOverlaps {
+		r.recording.SensorOverlaps = append(r.recording.SensorOverlaps, [2]uint64{p.a.id, p.b.id})
+	}
+	r.afterStep(w)
+
+	// Pending forces and pausing aren't part of the scene, so they count as inputs
+	for id, state := range r.last {
+		state.Acceleration = ZeroVec2()
+		state.RotationalAcceleration = 0
+		r.last[id] = state
+	}
+	r.paused = false
+
+	w.hook = r
+	return r, nil
+}
+
+func (r *Recorder) beforeStep(w *World, dt Scalar) {
+	var inputs []Input
+	if w.Paused != r.paused {
+		inputs = append(inputs, Input{Kind: "pause", Paused: w.Paused})
+	}
+
+	current := make(map[uint64]bool, len(w.Bodies))
+	for _, b := range w.Bodies {
+		current[b.id] = true
+		last, ok := r.last[b.id]
+		if !ok {
+			if _, err := newBodyJSON(b); err != nil {
+				// Can't be saved, but the rest of the recording is still useful
+				fmt.Fprintln(os.Stderr, err.Error())
+				continue
+			}
+			// A copy, since the game will keep moving the real one
+			inputs = append(inputs, Input{Kind: "add", ID: b.id, Body: b.clone()})
+
+			// Forces applied before its first step aren't part of the body
+			last = newBodyInputs(b)
+			last.Acceleration = ZeroVec2()
+			last.RotationalAcceleration = 0
+		}
+		if state := newBodyInputs(b); state != last {
+			inputs = append(inputs, Input{Kind: "set", ID: b.id, State: &state})
+		}
+	}
+	// w.Bodies is sorted by ID, so go through the deleted ones in the same order
+	for _, id := range sortedIDs(r.last) {
+		if !current[id] {
+			inputs = append(inputs, Input{Kind: "delete", ID: id})
+		}
+	}
+
+	steps := r.recording.Steps
+	if n := len(steps); n > 0 && len(inputs) == 0 && steps[n-1].DT == dt {
+		if steps[n-1].Count == 0 {
+			steps[n-1].Count = 1
+		}
+		steps[n-1].Count++
+		return
+	}
+	r.recording.Steps = append(steps, RecordedStep{DT: dt, Inputs: inputs})
+}
+
+func (r *Recorder) afterStep(w *World) {
+	r.last = make(map[uint64]BodyInputs, len(w.Bodies))
+	for _, b := range w.Bodies {
+		r.last[b.id] = newBodyInputs(b)
+	}
+	r.paused = w.Paused
+}
+
+// Stops recording and returns everything recorded
+func (r *Recorder) Stop() *Recording {
+	if r.world.hook == r {
+		r.world.hook = nil
+	}
+	return &r.recording
+}
+
+func sortedIDs(bodies map[uint64]BodyInputs) []uint64 {
+	ids := make([]uint64, 0, len(bodies))
+	for id := range bodies {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if rec.Version < 1 || rec.Version > RecordingVersion {
+		return nil, fmt.Errorf("physics2d: unsupported recording version %d", rec.Version)
+	}
+	return &rec, nil
+}
+
+func (rec *Recording) Save(path string) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+///////////////////////////////////////////////////////////////////////
+
+// Plays a recording back. Either call Step until it returns false, or let something
+// else (like a Stepper) call World.UpdatePhysics with the recorded dt.
+//
+// Pausing the World holds the playback where it is, so no recorded steps get used up.
+// While the recording itself is paused, the world stays paused whatever the game sets.
+type Replayer struct {
+	World     *World
+	recording *Recording
+	step      int  // Index into recording.Steps
+	repeat    int  // How many times the current RecordedStep has been played
+	paused    bool // Whether the recording has the world paused
+	err       error
+}
+
+func NewReplayer(rec *Recording) (*Replayer, error) {
+	var w World
+	if err := json.Unmarshal(rec.Scene, &w); err != nil {
+		return nil, err
+	}
+
+	bodies := make(map[uint64]*Body, len(w.Bodies))
+	for _, b := range w.Bodies {
+		bodies[b.id] = b
+	}
+	for _, rc := range rec.Contacts {
+		c, err := rc.collision(bodies)
+		if err != nil {
+			return nil, err
+		}
+		w.lastContactIndex[bodyPair{c.a, c.b}] = len(w.contacts)
+		w.contacts = append(w.contacts, c)
+	}
+	for _, ids := range rec.SensorOverlaps {
+		a, b := bodies[ids[0]], bodies[ids[1]]
+		if a == nil || b == nil {
+			return nil, fmt.Errorf("physics2d: recorded sensor overlap between missing bodies %d and %d", ids[0], ids[1])
+		}
+		w.sensorOverlaps = append(w.sensorOverlaps, bodyPair{a, b})
+	}
+
+	w.Deterministic = true
+	r := &Replayer{World: &w, recording: rec}
+	w.hook = r
+	return r, nil
+}
+
+// Runs the next recorded step. Returns false once the recording is over, but
+// doesn't get any closer to that while the World is paused.
+func (r *Replayer) Step() bool {
+	if r.Done() {
+		return false
+	}
+	r.World.UpdatePhysics(r.recording.Steps[r.step].DT)
+	return true
+}
+
+func (r *Replayer) Done() bool {
+	return r.step >= len(r.recording.Steps)
+}
+
+// The first thing that didn't match the recording, if anything
+func (r *Replayer) Err() error {
+	return r.err
+}
+
+func (r *Replayer) beforeStep(w *World, dt Scalar) {
+	if r.Done() {
+		return
+	}
+	// The game paused the playback, so this step doesn't happen
+	if w.Paused && !r.paused {
+		return
+	}
+	step := r.recording.Steps[r.step]
+	if step.DT != dt && r.err == nil {
+		r.err = fmt.Errorf("physics2d: replay stepped with dt %v instead of %v", dt, step.DT)
+	}
+	if r.repeat == 0 {
+		for _, input := range step.Inputs {
+			if err := r.apply(w, input); err != nil && r.err == nil {
+				r.err = err
+			}
+		}
+	}
+	w.Paused = r.paused
+	r.repeat++
+	if r.repeat >= max(step.Count, 1) {
+		r.step++
+		r.repeat = 0
+	}
+}
+
+func (r *Replayer) afterStep(w *World) {}
+
+func (r *Replayer) apply(w *World, input Input) error {
+	switch input.Kind {
+	case "pause":
+		r.paused = input.Paused
+	case "add":
+		if input.Body == nil {
+			return errors.New("physics2d: replay add is missing its body")
+		}
+		// Copied so the recording can be played more than once
+		b := input.Body.clone()
+		b.id = input.ID
+		w.nextID = max(w.nextID, b.id)
+		w.Bodies = append(w.Bodies, b)
+		w.sortBodies()
+	case "delete":
+		// The game might have deleted it already (like the demo does when bodies fall off)
+		w.Bodies = slices.DeleteFunc(w.Bodies, func(b *Body) bool {
+			return b.id == input.ID
+		})
+	case "set":
+		i := slices.IndexFunc(w.Bodies, func(b *Body) bool {
+			return b.id == input.ID
+		})
+		if i < 0 || input.State == nil {
+			return fmt.Errorf("physics2d: replay can't set body %d", input.ID)
+		}
+		input.State.apply(w.Bodies[i])
+	default:
+		return fmt.Errorf("physics2d: unknown replay input %q", input.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+//go:build !p2dfixed
+
+package physics2d
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReplayMatchesRecording(t *testing.T) {
+	w := testWorld(t)
+	recorder, err := NewRecorder(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const dt = 1.0 / 60
+	var hashes []uint64
+	for step := range 600 {
+		switch step {
+		case 50:
+			w.AddBody(NewPolygon(NewVec2(5, 6), []Vec2{{-0.3, -0.1}, {0.4, -0.2}, {0.2, 0.35}}, 0.3, 0.2, 1.9))
+		case 120:
+			w.Bodies[5].ApplyImpulse(NewVec2(1.3, 4.1))
+		case 200:
+			w.Paused = true
+		case 230:
+			w.Paused = false
+		case 300:
+			w.DeleteBody(7)
+		case 400:
+			w.Bodies[4].SetSurfaceSpeed(0.7)
+		}
+		w.UpdatePhysics(dt)
+		hashes = append(hashes, w.StateHash())
+	}
+
+	// Through JSON, like a recording saved by the demo
+	data, err := json.Marshal(recorder.Stop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := NewReplayer(&rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for step := 0; replayer.Step(); step++ {
+		if step >= len(hashes) {
+			t.Fatal("replay has more steps than the recording")
+		}
+		if got := replayer.World.StateHash(); got != hashes[step] {
+			t.Fatalf("replay split from the recording at step %d", step)
+		}
+	}
+	if err := replayer.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Records n steps of the world, and what its state hash was after each one
+func recordSteps(t *testing.T, w *World, n int) (*Recording, []uint64, []int) {
+	t.Helper()
+	recorder, err := NewRecorder(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hashes []uint64
+	var sensorEvents []int
+	for range n {
+		w.UpdatePhysics(1.0 / 60)
+		hashes = append(hashes, w.StateHash())
+		sensorEvents = append(sensorEvents, len(w.SensorEvents))
+	}
+
+	data, err := json.Marshal(recorder.Stop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatal(err)
+	}
+	return &rec, hashes, sensorEvents
+}
+
+// Starting in the middle of things, with a ball halfway through a one-way platform
+// and inside a sensor. Both only come out right if the contacts were recorded.
+func TestReplayStartsWithContacts(t *testing.T) {
+	floor := NewBox(NewVec2(5, 0.5), NewVec2(10, 1), 0, 0, 0)
+	platform := NewBox(NewVec2(5, 4), NewVec2(2, 0.2), 0, 0, 0)
+	platform.SetOneWay(NewVec2(0, 1))
+	zone := NewBox(NewVec2(5, 4), NewVec2(4, 4), 0, 0, 0)
+	zone.SetSensor(true)
+	ball := NewBall(NewVec2(5, 2), 0.3, 0, 1)
+	ball.ApplyImpulse(NewVec2(0, 6.4))
+	w := NewWorld([]*Body{floor, platform, zone, ball}, NewVec2(10, 8), 9.8, 8)
+
+	// Up until the ball starts falling back down while it's still in the platform
+	passing := false
+	for range 120 {
+		w.UpdatePhysics(1.0 / 60)
+		passing = len(w.contacts) == 1 && w.contacts[0].passingThrough && ball.velocity.y < 0
+		if passing {
+			break
+		}
+	}
+	if !passing || len(w.sensorOverlaps) != 1 {
+		t.Fatal("the ball never fell back through the platform inside the sensor")
+	}
+
+	rec, hashes, sensorEvents := recordSteps(t, &w, 120)
+	replayer, err := NewReplayer(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for step := 0; replayer.Step(); step++ {
+		if got := replayer.World.StateHash(); got != hashes[step] {
+			t.Fatalf("replay split from the recording at step %d", step)
+		}
+		if got := len(replayer.World.SensorEvents); got != sensorEvents[step] {
+			t.Fatalf("step %d: %d sensor events instead of %d", step, got, sensorEvents[step])
+		}
+	}
+}
+
+// Pausing the world during a replay shouldn't skip any of the recording
+func TestPausingReplayHoldsPlayback(t *testing.T) {
+	w := testWorld(t)
+	rec, hashes, _ := recordSteps(t, &w, 120)
+
+	replayer, err := NewReplayer(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for step := range len(hashes) {
+		if step == 60 {
+			replayer.World.Paused = true
+			for range 30 {
+				replayer.World.UpdatePhysics(1.0 / 60)
+			}
+			replayer.World.Paused = false
+		}
+		if !replayer.Step() {
+			t.Fatalf("replay ended after %d steps instead of %d", step, len(hashes))
+		}
+		if got := replayer.World.StateHash(); got != hashes[step] {
+			t.Fatalf("replay split from the recording at step %d", step)
+		}
+	}
+	if !replayer.Done() {
+		t.Fatal("replay has more steps than the recording")
+	}
+}
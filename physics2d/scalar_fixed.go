@@ -0,0 +1,72 @@
+//go:build p2dfixed
+
+package physics2d
+
+import "github.com/lwbuchanan/Physics2D/physics2d/fixed"
+
+// Built with -tags p2dfixed, every number in the engine is Q32.32 fixed point. Integer
+// math rounds the same way on every machine, so lockstep peers can't drift apart even
+// on different CPUs (see determinism.go). Values have to stay well under a million.
+type Scalar = fixed.Fixed
+
+const (
+	one       Scalar = fixed.One
+	maxScalar Scalar = fixed.MaxFixed
+	pi        Scalar = fixed.Pi
+)
+
+func mul(a, b Scalar) Scalar {
+	return a.Mul(b)
+}
+
+func div(a, b Scalar) Scalar {
+	return a.Div(b)
+}
+
+func sqrt(a Scalar) Scalar {
+	return a.Sqrt()
+}
+
+func abs(a Scalar) Scalar {
+	return a.Abs()
+}
+
+func copysign(a, sign Scalar) Scalar {
+	if sign < 0 {
+		return -a.Abs()
+	}
+	return a.Abs()
+}
+
+func sin(a Scalar) Scalar {
+	return fixed.Sin(a)
+}
+
+func cos(a Scalar) Scalar {
+	return fixed.Cos(a)
+}
+
+func atan2(y, x Scalar) Scalar {
+	return fixed.Atan2(y, x)
+}
+
+func fromInt(i int) Scalar {
+	return fixed.FromInt(i)
+}
+
+// Only for constants and setting things up, see fixed.FromFloat
+func fromFloat(f float64) Scalar {
+	return fixed.FromFloat(f)
+}
+
+func toFloat(s Scalar) float64 {
+	return s.Float()
+}
+
+func scalarBits(s Scalar) uint64 {
+	return uint64(s)
+}
+
+func scalarFromBits(b uint64) Scalar {
+	return Scalar(b)
+}
@@ -0,0 +1,76 @@
+//go:build p2dfixed
+
+package physics2d
+
+import "testing"
+
+func fixedTestWorld(t *testing.T) World {
+	t.Helper()
+	bodies := []*Body{
+		NewBox(NewVec2(fromInt(5), fromFloat(0.25)), NewVec2(fromFloat(9.5), fromFloat(0.5)), 0, fromFloat(0.3), 0),
+		NewBox(NewVec2(fromFloat(0.25), fromInt(4)), NewVec2(fromFloat(0.5), fromInt(7)), 0, fromFloat(0.3), 0),
+		NewBox(NewVec2(fromFloat(9.75), fromInt(4)), NewVec2(fromFloat(0.5), fromInt(7)), 0, fromFloat(0.3), 0),
+	}
+	triangle := []Vec2{
+		NewVec2(fromFloat(-0.3), fromFloat(-0.2)),
+		NewVec2(fromFloat(0.5), fromFloat(-0.25)),
+		NewVec2(fromFloat(0.1), fromFloat(0.4)),
+	}
+	for i := range 12 {
+		position := NewVec2(fromFloat(1.2+float64(i%6)*1.4), fromFloat(2+float64(i/6)*1.5))
+		var b *Body
+		if i%2 == 0 {
+			b = NewBall(position, fromFloat(0.2+0.03*float64(i%4)), fromFloat(0.4), fromFloat(0.7+0.5*float64(i)))
+		} else {
+			b = NewPolygon(position, triangle, fromFloat(0.1*float64(i)), fromFloat(0.2), fromFloat(1.3))
+		}
+		if b == nil {
+			t.Fatalf("body %d didn't build", i)
+		}
+		b.SetFriction(fromFloat(0.6), fromFloat(0.4))
+		bodies = append(bodies, b)
+	}
+	w := NewWorld(bodies, NewVec2(fromInt(10), fromInt(8)), fromFloat(9.8), 8)
+	w.Deterministic = true
+	return w
+}
+
+// The whole point of the fixed build: the same world stepped twice ends up in exactly the same place
+func TestFixedWorldsStepTheSame(t *testing.T) {
+	a := fixedTestWorld(t)
+	b := fixedTestWorld(t)
+	dt := fromFloat(1.0 / 60)
+	for step := range 300 {
+		a.UpdatePhysics(dt)
+		b.UpdatePhysics(dt)
+		if a.StateHash() != b.StateHash() {
+			t.Fatalf("step %d: the worlds hash differently", step)
+		}
+	}
+	sa, sb := a.State(), b.State()
+	for i := range sa.Bodies {
+		if sa.Bodies[i] != sb.Bodies[i] {
+			t.Fatalf("body %d ended up in a different state", sa.Bodies[i].ID)
+		}
+	}
+}
+
+// Catches a helper that got the scale wrong, which would still be deterministic
+func TestFixedBallSettles(t *testing.T) {
+	ground := NewBox(NewVec2(fromInt(5), fromFloat(0.5)), NewVec2(fromInt(10), fromInt(1)), 0, 0, 0)
+	ball := NewBall(NewVec2(fromInt(5), fromInt(3)), fromFloat(0.5), 0, fromInt(1))
+	w := NewWorld([]*Body{ground, ball}, NewVec2(fromInt(10), fromInt(10)), fromFloat(9.8), 8)
+	for range 180 {
+		w.UpdatePhysics(fromFloat(1.0 / 60))
+	}
+	if y := toFloat(ball.Position().Y()); y < 1.45 || y > 1.55 {
+		t.Fatalf("the ball came to rest at %v instead of on the ground at 1.5", y)
+	}
+}
+
+// A zero World has no time steps, and that mustn't turn into a divide by zero
+func TestFixedZeroWorldSteps(t *testing.T) {
+	var w World
+	w.AddBody(NewBall(NewVec2(fromInt(1), fromInt(1)), fromFloat(0.5), 0, fromInt(1)))
+	w.UpdatePhysics(fromFloat(1.0 / 60))
+}
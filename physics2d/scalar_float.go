@@ -0,0 +1,73 @@
+//go:build !p2dfixed
+
+package physics2d
+
+import "math"
+
+// Every number in the engine is a Scalar. Normally that's just a float64, but building
+// with -tags p2dfixed swaps in fixed-point numbers instead (see scalar_fixed.go).
+//
+// Go can't overload operators, so adding, subtracting and comparing Scalars works as
+// usual, but multiplying or dividing two of them has to go through mul and div. Scaling
+// by an integer constant (x * 2, x / 2) is fine in both builds, but any other constant
+// has to be converted with fromFloat or fromInt first.
+type Scalar = float64
+
+const (
+	one       Scalar = 1
+	maxScalar Scalar = math.MaxFloat64
+	pi        Scalar = math.Pi
+)
+
+func mul(a, b Scalar) Scalar {
+	return a * b
+}
+
+func div(a, b Scalar) Scalar {
+	return a / b
+}
+
+func sqrt(a Scalar) Scalar {
+	return math.Sqrt(a)
+}
+
+func abs(a Scalar) Scalar {
+	return math.Abs(a)
+}
+
+func copysign(a, sign Scalar) Scalar {
+	return math.Copysign(a, sign)
+}
+
+func sin(a Scalar) Scalar {
+	return math.Sin(a)
+}
+
+func cos(a Scalar) Scalar {
+	return math.Cos(a)
+}
+
+func atan2(y, x Scalar) Scalar {
+	return math.Atan2(y, x)
+}
+
+func fromInt(i int) Scalar {
+	return float64(i)
+}
+
+func fromFloat(f float64) Scalar {
+	return f
+}
+
+func toFloat(s Scalar) float64 {
+	return s
+}
+
+// For hashing and saving states exactly
+func scalarBits(s Scalar) uint64 {
+	return math.Float64bits(s)
+}
+
+func scalarFromBits(b uint64) Scalar {
+	return math.Float64frombits(b)
+}
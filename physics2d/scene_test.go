@@ -0,0 +1,109 @@
+//go:build !p2dfixed
+
+package physics2d
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Lopsided shapes and masses that don't survive 1/(1/m), which is where saving
+// used to lose bits
+func testWorld(t *testing.T) World {
+	t.Helper()
+	bodies := []*Body{
+		NewBox(NewVec2(5, 0.25), NewVec2(9.5, 0.5), 0, 0.3, 0),
+		NewBox(NewVec2(0.25, 4), NewVec2(0.5, 7), 0, 0.3, 0),
+		NewBox(NewVec2(9.75, 4), NewVec2(0.5, 7), 0, 0.3, 0),
+	}
+	shapes := [][]Vec2{
+		{{-0.3, -0.2}, {0.5, -0.25}, {0.1, 0.4}},
+		{{-0.4, -0.3}, {0.35, -0.3}, {0.45, 0.1}, {0, 0.42}, {-0.5, 0.05}},
+		{{-0.2, -0.2}, {0.3, -0.1}, {0.2, 0.3}, {-0.25, 0.15}},
+	}
+	masses := []float64{0.7, 1.3, 2.9, 3.7, 0.41, 5.3, 1.1}
+	for i := range 14 {
+		position := NewVec2(1.2+float64(i%7)*1.2, 2+float64(i/7)*1.5)
+		mass := masses[i%len(masses)]
+		var b *Body
+		if i%3 == 0 {
+			b = NewBall(position, 0.17+0.03*float64(i%4), 0.4, mass)
+		} else {
+			b = NewPolygon(position, shapes[i%len(shapes)], 0.1*float64(i), 0.2, mass)
+		}
+		if b == nil {
+			t.Fatalf("body %d didn't build", i)
+		}
+		b.SetFriction(0.6, 0.4)
+		bodies = append(bodies, b)
+	}
+	w := NewWorld(bodies, NewVec2(10, 8), 9.8, 8)
+	w.Deterministic = true
+	return w
+}
+
+func TestSceneRoundTripStepsTheSame(t *testing.T) {
+	original := testWorld(t)
+	data, err := json.Marshal(&original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded World
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	loaded.Deterministic = true
+
+	if original.StateHash() != loaded.StateHash() {
+		t.Fatal("hashes differ right after loading")
+	}
+	for step := range 600 {
+		original.UpdatePhysics(1.0 / 60)
+		loaded.UpdatePhysics(1.0 / 60)
+		if original.StateHash() != loaded.StateHash() {
+			t.Fatalf("hashes split at step %d", step)
+		}
+	}
+}
+
+func TestSceneKeepsSavedVertices(t *testing.T) {
+	original := testWorld(t)
+	data, err := json.Marshal(&original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded World
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range original.Bodies {
+		l := loaded.Bodies[i]
+		if b.Mass() != l.Mass() || b.MomentOfIntertia() != l.MomentOfIntertia() {
+			t.Errorf("body %d: mass or inertia changed", i)
+		}
+		for j, v := range b.localVertices() {
+			if v != l.localVertices()[j] {
+				t.Errorf("body %d vertex %d: %v loaded as %v", i, j, v, l.localVertices()[j])
+			}
+		}
+	}
+}
+
+// Deterministic worlds sort by ID every step, so loading should already have done it
+func TestSceneLoadsInIDOrder(t *testing.T) {
+	data := []byte(`{"version": 1, "dimensions": [10, 10], "bodies": [
+		{"id": 3, "shape": "ball", "radius": 0.5, "position": [1, 1]},
+		{"id": 1, "shape": "ball", "radius": 0.5, "position": [3, 1]},
+		{"shape": "ball", "radius": 0.5, "position": [5, 1]},
+		{"id": 2, "shape": "ball", "radius": 0.5, "position": [7, 1]}
+	]}`)
+	var w World
+	if err := json.Unmarshal(data, &w); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range w.Bodies {
+		if b.ID() != uint64(i+1) {
+			t.Fatalf("body %d has id %d", i, b.ID())
+		}
+	}
+}
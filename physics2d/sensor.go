@@ -0,0 +1,43 @@
+package physics2d
+
+import "slices"
+
+// Reported once when a body starts overlapping a sensor, and once when it stops
+type SensorEvent struct {
+	Sensor *Body
+	Other  *Body
+	Begin  bool // False when the overlap ended
+}
+
+type bodyPair struct {
+	a, b *Body
+}
+
+// Sensors are checked every time step, but we only want one event per tick,
+// so overlaps are collected here and compared to last tick's afterwards
+func (w *World) addSensorOverlap(a, b *Body) {
+	if b.sensor {
+		a, b = b, a
+	}
+	pair := bodyPair{a, b}
+	if !slices.Contains(w.sensorBuffer, pair) {
+		w.sensorBuffer = append(w.sensorBuffer, pair)
+	}
+}
+
+func (w *World) updateSensorEvents() {
+	w.SensorEvents = w.SensorEvents[:0]
+	for _, p := range w.sensorBuffer {
+		if !slices.Contains(w.sensorOverlaps, p) {
+			w.SensorEvents = append(w.SensorEvents, SensorEvent{p.a, p.b, true})
+		}
+	}
+	for _, p := range w.sensorOverlaps {
+		if !slices.Contains(w.sensorBuffer, p) {
+			w.SensorEvents = append(w.SensorEvents, SensorEvent{p.a, p.b, false})
+		}
+	}
+
+	// Swap so that we can reuse last tick's slice next time
+	w.sensorOverlaps, w.sensorBuffer = w.sensorBuffer, w.sensorOverlaps
+}
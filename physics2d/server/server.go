@@ -0,0 +1,368 @@
+// Package server steps a physics2d world and streams it to browsers over a
+// WebSocket, so a long running sim can be watched (and poked at) from anywhere that
+// can reach it. It only uses the standard library, including its own WebSocket code.
+//
+// GET / serves a small canvas viewer and GET /ws is the WebSocket. Add ?format=binary
+// to get states in the binary format from state.go (deltas after the first frame)
+// instead of JSON. Messages from the server are JSON text, except for binary states:
+//
+//	{"type": "scene", "scene": {...}}                   the whole world, same as a scene file
+//	{"type": "status", "step": 120, "paused": false}
+//	{"type": "state", "step": 121, "bodies": [...]}     one physics2d.BodyState per body
+//	{"type": "error", "message": "..."}
+//
+// A scene is sent when a client connects and again whenever bodies are added, followed
+// by a full state. Clients can send these commands:
+//
+//	{"type": "spawn", "body": {"shape": "ball", "radius": 0.2, "mass": 1, "position": [7, 4]}}
+//	{"type": "impulse", "id": 3, "impulse": [0, 5]}
+//	{"type": "pause", "paused": true}                   leave out paused to toggle
+//	{"type": "step"}                                    one step while paused
+//
+// Anyone who can connect can change the world, so keep it on localhost (or behind
+// something that checks who's asking).
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+)
+
+//go:embed viewer.html
+var viewerHTML []byte
+
+// How many messages can wait for a slow client before it starts missing states
+const clientQueueSize = 16
+
+type Server struct {
+	world   *p2d.World
+	stepper *p2d.Stepper
+	steps   int // Steps actually taken, so pauses don't count
+
+	// States sent to clients per second
+	FrameRate float64
+
+	join     chan *client
+	leave    chan *client
+	commands chan command
+	clients  map[*client]bool
+	stopped  chan struct{} // Closed when Run returns
+}
+
+// Only Run touches the world after this, so don't use it from anywhere else
+func NewServer(world *p2d.World, fixedStep float64) (*Server, error) {
+	stepper, err := p2d.NewStepper(world, fixedStep)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		world:     world,
+		stepper:   stepper,
+		FrameRate: 30,
+		join:      make(chan *client),
+		leave:     make(chan *client),
+		commands:  make(chan command),
+		clients:   make(map[*client]bool),
+		stopped:   make(chan struct{}),
+	}, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(viewerHTML)
+	})
+	mux.HandleFunc("/ws", s.serveWebSocket)
+	return mux
+}
+
+// Steps the world in real time and sends it to everyone until ctx is done
+func (s *Server) Run(ctx context.Context) error {
+	if s.FrameRate <= 0 {
+		return errors.New("physics2d/server: frame rate must be positive")
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / s.FrameRate))
+	defer ticker.Stop()
+	defer close(s.stopped)
+	last := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			for c := range s.clients {
+				c.hangUp()
+			}
+			return ctx.Err()
+
+		case c := <-s.join:
+			s.clients[c] = true
+			c.prev = nil
+			if !c.queue(s.sceneMessage()) || !c.queue(s.statusMessage()) {
+				c.hangUp()
+				delete(s.clients, c)
+			}
+
+		case c := <-s.leave:
+			delete(s.clients, c)
+
+		case cmd := <-s.commands:
+			if err := s.apply(cmd); err != nil {
+				data, _ := json.Marshal(errorJSON{"error", err.Error()})
+				cmd.from.queue(wsMessage{opText, data})
+			}
+
+		case now := <-ticker.C:
+			paused := s.world.Paused
+			steps := s.stepper.Advance(now.Sub(last).Seconds())
+			last = now
+			if !paused {
+				s.steps += steps
+			}
+			s.broadcastState()
+		}
+	}
+}
+
+func (s *Server) apply(cmd command) error {
+	switch cmd.Type {
+	case "spawn":
+		if cmd.Body == nil {
+			return errors.New("spawn needs a body")
+		}
+		// Two bodies with the same ID would confuse every client
+		if cmd.Body.ID() != 0 {
+			return errors.New("spawned bodies get their ID from the world")
+		}
+		s.world.AddBody(cmd.Body)
+		s.broadcast(s.sceneMessage(), true)
+	case "impulse":
+		b := s.body(cmd.ID)
+		if b == nil {
+			return fmt.Errorf("no body with id %d", cmd.ID)
+		}
+		b.ApplyImpulse(cmd.Impulse)
+	case "pause":
+		if cmd.Paused != nil {
+			s.world.Paused = *cmd.Paused
+		} else {
+			s.world.Paused = !s.world.Paused
+		}
+		s.broadcast(s.statusMessage(), false)
+	case "step":
+		if !s.world.Paused {
+			return errors.New("can only step while paused")
+		}
+		s.world.Paused = false
+		s.world.UpdatePhysics(s.stepper.FixedStep)
+		s.world.Paused = true
+		s.steps++
+		s.broadcast(s.statusMessage(), false)
+	default:
+		return fmt.Errorf("unknown command %q", cmd.Type)
+	}
+	return nil
+}
+
+func (s *Server) body(id uint64) *p2d.Body {
+	for _, b := range s.world.Bodies {
+		if b.ID() == id {
+			return b
+		}
+	}
+	return nil
+}
+
+// Sends to every client. A new scene means new bodies, so the next binary state
+// has to be a full one.
+func (s *Server) broadcast(m wsMessage, newScene bool) {
+	for c := range s.clients {
+		if newScene {
+			c.prev = nil
+		}
+		// Scenes and statuses can't be skipped like states can, so a client that
+		// can't keep up with them is too far behind to bother with
+		if !c.queue(m) {
+			c.hangUp()
+			delete(s.clients, c)
+		}
+	}
+}
+
+func (s *Server) broadcastState() {
+	state := s.world.State()
+	var jsonMessage *wsMessage
+	for c := range s.clients {
+		var m wsMessage
+		if c.binary {
+			m = wsMessage{opBinary, p2d.AppendState(nil, state, c.prev)}
+		} else {
+			// Everyone using JSON gets the same bytes
+			if jsonMessage == nil {
+				data, _ := json.Marshal(stateJSON{"state", s.steps, state.Bodies})
+				jsonMessage = &wsMessage{opText, data}
+			}
+			m = *jsonMessage
+		}
+		// If the queue is full the client misses this state. Deltas are against the
+		// last state that was actually queued, so that's fine.
+		if c.queue(m) {
+			c.prev = &state
+		}
+	}
+}
+
+func (s *Server) sceneMessage() wsMessage {
+	scene, err := json.Marshal(s.world)
+	if err != nil {
+		data, _ := json.Marshal(errorJSON{"error", err.Error()})
+		return wsMessage{opText, data}
+	}
+	data, _ := json.Marshal(sceneJSON{"scene", scene})
+	return wsMessage{opText, data}
+}
+
+func (s *Server) statusMessage() wsMessage {
+	data, _ := json.Marshal(statusJSON{"status", s.steps, s.world.Paused})
+	return wsMessage{opText, data}
+}
+
+type sceneJSON struct {
+	Type  string          `json:"type"`
+	Scene json.RawMessage `json:"scene"`
+}
+
+type statusJSON struct {
+	Type   string `json:"type"`
+	Step   int    `json:"step"`
+	Paused bool   `json:"paused"`
+}
+
+type stateJSON struct {
+	Type   string          `json:"type"`
+	Step   int             `json:"step"`
+	Bodies []p2d.BodyState `json:"bodies"`
+}
+
+type errorJSON struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type command struct {
+	Type    string    `json:"type"` // "spawn", "impulse", "pause" or "step"
+	Body    *p2d.Body `json:"body"`
+	ID      uint64    `json:"id"`
+	Impulse p2d.Vec2  `json:"impulse"`
+	Paused  *bool     `json:"paused"`
+
+	from *client
+}
+
+///////////////////////////////////////////////////////////////////////
+
+type client struct {
+	conn   *wsConn
+	binary bool
+	send   chan wsMessage
+	done   chan struct{} // Closed once the connection is gone
+
+	prev *p2d.State // Last state queued, only used by Run
+}
+
+// Never blocks, so one slow browser can't hold up the world
+func (c *client) queue(m wsMessage) bool {
+	select {
+	case <-c.done:
+		return false
+	case c.send <- m:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *client) hangUp() {
+	c.conn.closeWith(closeNormal)
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "binary" {
+		http.Error(w, "format must be json or binary", http.StatusBadRequest)
+		return
+	}
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	c := &client{
+		conn:   conn,
+		binary: format == "binary",
+		send:   make(chan wsMessage, clientQueueSize),
+		done:   make(chan struct{}),
+	}
+	defer close(c.done)
+	defer conn.closeWith(closeNormal)
+
+	// Run might have stopped, in which case nobody is listening
+	select {
+	case s.join <- c:
+	case <-s.stopped:
+		return
+	}
+	go c.writeLoop()
+
+	for {
+		m, err := conn.readMessage()
+		if err != nil {
+			break
+		}
+		var cmd command
+		if m.opcode != opText {
+			err = errors.New("commands have to be JSON text")
+		} else {
+			err = json.Unmarshal(m.data, &cmd)
+		}
+		if err != nil {
+			data, _ := json.Marshal(errorJSON{"error", err.Error()})
+			c.queue(wsMessage{opText, data})
+			continue
+		}
+		cmd.from = c
+		select {
+		case s.commands <- cmd:
+		case <-s.stopped:
+			return
+		}
+	}
+	select {
+	case s.leave <- c:
+	case <-s.stopped:
+	}
+}
+
+func (c *client) writeLoop() {
+	for {
+		select {
+		case m := <-c.send:
+			if err := c.conn.writeMessage(m); err != nil {
+				c.conn.closeWith(closeNormal)
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
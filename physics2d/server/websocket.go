@@ -0,0 +1,282 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Just enough of RFC 6455 for a browser to talk to us: the opening handshake, masked
+// frames from the client, fragmentation, ping/pong and the closing handshake. No
+// extensions (so no compression) and no subprotocols.
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes we send
+const (
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeInvalidData   = 1007
+	closeTooBig        = 1009
+)
+
+// Commands are tiny, so anything bigger than this is a mistake
+const maxMessageSize = 1 << 20
+
+// From the RFC, for working out Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var errClosed = errors.New("physics2d/server: websocket closed")
+
+type wsMessage struct {
+	opcode byte
+	data   []byte
+}
+
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeLock sync.Mutex // Pongs are written by the reader while frames go out from the writer
+	closed    bool       // Only touched while holding writeLock
+}
+
+// Does the opening handshake. On failure it has already written an HTTP error.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	fail := func(status int, reason string) (*wsConn, error) {
+		http.Error(w, reason, status)
+		return nil, errors.New("physics2d/server: " + reason)
+	}
+	if r.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "websocket handshake must be a GET")
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "bad Sec-WebSocket-Key")
+	}
+	// Browsers let any page open a websocket to localhost, so only take
+	// connections from pages we served ourselves
+	if origin := r.Header.Get("Origin"); origin != "" && !sameHost(origin, r.Host) {
+		return fail(http.StatusForbidden, "cross-origin websocket refused")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return fail(http.StatusInternalServerError, "connection can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// The handshake might have left the read/write deadlines of the HTTP server set
+	conn.SetDeadline(time.Time{})
+
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, reader: rw.Reader}, nil
+}
+
+// Headers like Connection can be a comma separated list ("keep-alive, Upgrade")
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameHost(origin, host string) bool {
+	_, rest, ok := strings.Cut(origin, "://")
+	return ok && strings.EqualFold(rest, host)
+}
+
+// Reads the next whole text or binary message. Control frames in between are handled
+// here. When the client closes, the close is echoed and errClosed is returned.
+func (c *wsConn) readMessage() (wsMessage, error) {
+	var message wsMessage
+	started := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return wsMessage{}, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return wsMessage{}, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := uint16(closeNormal)
+			if len(payload) >= 2 {
+				code = binary.BigEndian.Uint16(payload)
+			}
+			c.closeWith(code)
+			return wsMessage{}, errClosed
+		case opText, opBinary:
+			if started {
+				return wsMessage{}, c.fail(closeProtocolError, "new message before the last one finished")
+			}
+			started = true
+			message.opcode = opcode
+		case opContinuation:
+			if !started {
+				return wsMessage{}, c.fail(closeProtocolError, "continuation without a message")
+			}
+		default:
+			return wsMessage{}, c.fail(closeProtocolError, "unknown opcode")
+		}
+
+		if len(message.data)+len(payload) > maxMessageSize {
+			return wsMessage{}, c.fail(closeTooBig, "message too big")
+		}
+		message.data = append(message.data, payload...)
+		if fin {
+			if message.opcode == opText && !utf8.Valid(message.data) {
+				return wsMessage{}, c.fail(closeInvalidData, "text message isn't UTF-8")
+			}
+			return message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(closeProtocolError, "reserved bits set")
+	}
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	if !masked {
+		return false, 0, nil, c.fail(closeProtocolError, "frames from the client must be masked")
+	}
+	isControl := opcode&0x8 != 0
+	if isControl && (!fin || length > 125) {
+		return false, 0, nil, c.fail(closeProtocolError, "bad control frame")
+	}
+
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, c.fail(closeTooBig, "frame too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// We never fragment or mask (servers mustn't mask)
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.closed {
+		return errClosed
+	}
+
+	header := make([]byte, 0, 10)
+	header = append(header, 0x80|opcode)
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	// A client that stops reading shouldn't be able to hang us forever
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	buffers := net.Buffers{header, payload}
+	_, err := buffers.WriteTo(c.conn)
+	return err
+}
+
+func (c *wsConn) writeMessage(m wsMessage) error {
+	return c.writeFrame(m.opcode, m.data)
+}
+
+// Sends a close frame (if we haven't yet) and hangs up
+func (c *wsConn) closeWith(code uint16) {
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	c.writeFrame(opClose, payload)
+
+	c.writeLock.Lock()
+	c.closed = true
+	c.writeLock.Unlock()
+	c.conn.Close()
+}
+
+func (c *wsConn) fail(code uint16, reason string) error {
+	c.closeWith(code)
+	return errors.New("physics2d/server: " + reason)
+}
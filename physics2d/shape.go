@@ -0,0 +1,249 @@
+package physics2d
+
+import (
+	"errors"
+	"slices"
+)
+
+// Everything the engine needs to know about a shape. Shapes are described in
+// local space, centered on their center of mass, and the body supplies the
+// transform that puts them in the world.
+//
+// Any convex shape can be added by implementing this. Collisions with other shapes
+// fall back on GJK and EPA, and faster routines can be added with RegisterCollider.
+type Shape interface {
+	// Identifies the shape in the collision table. Custom shapes should get theirs from NewShapeType.
+	Type() BodyShape
+
+	// The point furthest in the direction, in world space
+	Support(xf Transform, direction Vec2) Vec2
+
+	// Axis aligned bounding box in world space, as the bottom left and top right corners
+	AABB(xf Transform) (Vec2, Vec2)
+
+	// In m2, so bodies can work out their density
+	Area() Scalar
+
+	// Around the center of mass, for a shape of the given mass in kg
+	MomentOfInertia(mass Scalar) Scalar
+
+	// Distance along the normalized direction to where the ray enters the shape, and the
+	// surface normal there. Rays that start inside the shape don't hit it.
+	RayCast(xf Transform, origin, direction Vec2, maxDistance Scalar) (Scalar, Vec2, bool)
+}
+
+var nextShapeType = PointMass + 1
+
+// Hands out a new BodyShape for a custom Shape to use as its Type. It isn't
+// locked, so only call it from an init function (or a package level var).
+func NewShapeType() BodyShape {
+	t := nextShapeType
+	nextShapeType++
+	return t
+}
+
+///////////////////////////////////////////////////////////////////////
+
+type Circle struct {
+	Radius Scalar
+}
+
+func (c *Circle) Type() BodyShape {
+	return Ball
+}
+
+func (c *Circle) Support(xf Transform, direction Vec2) Vec2 {
+	if direction.LengthSquared() == 0 {
+		return xf.Pos
+	}
+	return xf.Pos.Add(direction.Normalize().ScaleMult(c.Radius))
+}
+
+func (c *Circle) AABB(xf Transform) (Vec2, Vec2) {
+	r := NewVec2(c.Radius, c.Radius)
+	return xf.Pos.Sub(r), xf.Pos.Add(r)
+}
+
+func (c *Circle) Area() Scalar {
+	return mul(mul(pi, c.Radius), c.Radius)
+}
+
+func (c *Circle) MomentOfInertia(mass Scalar) Scalar {
+	return mul(mul(mass/2, c.Radius), c.Radius)
+}
+
+func (c *Circle) RayCast(xf Transform, origin, direction Vec2, maxDistance Scalar) (Scalar, Vec2, bool) {
+	return rayCastCircle(xf.Pos, c.Radius, origin, direction, maxDistance)
+}
+
+///////////////////////////////////////////////////////////////////////
+
+type ConvexPolygon struct {
+	vertices []Vec2
+}
+
+// The vertices get moved so that the centroid is at the origin, which
+// is where the body's position will be
+func NewConvexPolygon(vertices []Vec2) (*ConvexPolygon, error) {
+	if err := checkConvex(vertices); err != nil {
+		return nil, err
+	}
+	centroid, _ := polygonCentroid(vertices)
+	centered := make([]Vec2, len(vertices))
+	for i, v := range vertices {
+		centered[i] = v.Sub(centroid)
+	}
+	return &ConvexPolygon{centered}, nil
+}
+
+// Like NewConvexPolygon, but vertices that are already centered (as far as rounding
+// allows) are kept exactly as they are. Centering them again would change the last
+// bits, which is enough to make a saved and reloaded world step differently.
+func newConvexPolygonExact(vertices []Vec2) (*ConvexPolygon, error) {
+	if err := checkConvex(vertices); err != nil {
+		return nil, err
+	}
+	var size Scalar
+	for _, v := range vertices {
+		size = max(size, v.Length())
+	}
+	if centroid, _ := polygonCentroid(vertices); centroid.Length() > mul(size, fromFloat(1e-9)) {
+		return NewConvexPolygon(vertices)
+	}
+	return &ConvexPolygon{slices.Clone(vertices)}, nil
+}
+
+func checkConvex(vertices []Vec2) error {
+	if len(vertices) < 3 {
+		return errors.New("physics2d: polygon needs at least 3 vertices")
+	}
+
+	// Repeated vertices would make an edge with no direction, and so a NaN normal
+	for i, v := range vertices {
+		if v == vertices[(i+1)%len(vertices)] {
+			return errors.New("physics2d: polygon has repeated vertices")
+		}
+	}
+
+	// Every turn has to go the same way, or it isn't convex
+	var winding Scalar
+	for i := range len(vertices) {
+		vCurr := vertices[i]
+		vNext := vertices[(i+1)%len(vertices)]
+		vAfter := vertices[(i+2)%len(vertices)]
+		turn := vNext.Sub(vCurr).Cross(vAfter.Sub(vNext))
+		if mul(turn, winding) < 0 {
+			return errors.New("physics2d: polygon is not convex")
+		}
+		if turn != 0 {
+			winding = turn
+		}
+	}
+	if winding == 0 {
+		return errors.New("physics2d: polygon has no area")
+	}
+	return nil
+}
+
+// Centroid and (signed) area, by splitting the polygon into triangles from the origin
+func polygonCentroid(vertices []Vec2) (Vec2, Scalar) {
+	var area Scalar
+	centroid := ZeroVec2()
+	for i, vCurr := range vertices {
+		vNext := vertices[(i+1)%len(vertices)]
+		triangleArea := vCurr.Cross(vNext) / 2
+		area += triangleArea
+		centroid = centroid.Add(vCurr.Add(vNext).ScaleMult(triangleArea / 3))
+	}
+	return centroid.ScaleDivide(area), area
+}
+
+// In local space
+func (p *ConvexPolygon) Vertices() []Vec2 {
+	return p.vertices
+}
+
+func (p *ConvexPolygon) Type() BodyShape {
+	return Polygon
+}
+
+func (p *ConvexPolygon) Support(xf Transform, direction Vec2) Vec2 {
+	// Rotate the direction into local space instead of moving every vertex
+	local := xf.InverseRotate(direction)
+	furthest := p.vertices[0]
+	for _, v := range p.vertices[1:] {
+		if v.Dot(local) > furthest.Dot(local) {
+			furthest = v
+		}
+	}
+	return furthest.Transform(xf)
+}
+
+func (p *ConvexPolygon) AABB(xf Transform) (Vec2, Vec2) {
+	lower := NewVec2(maxScalar, maxScalar)
+	upper := NewVec2(-maxScalar, -maxScalar)
+	for _, v := range p.vertices {
+		v = v.Transform(xf)
+		lower = NewVec2(min(lower.x, v.x), min(lower.y, v.y))
+		upper = NewVec2(max(upper.x, v.x), max(upper.y, v.y))
+	}
+	return lower, upper
+}
+
+func (p *ConvexPolygon) Area() Scalar {
+	_, area := polygonCentroid(p.vertices)
+	return abs(area)
+}
+
+// Adds up the moment of each triangle from the centroid. For a box this
+// comes out to the usual m(w^2 + h^2)/12.
+func (p *ConvexPolygon) MomentOfInertia(mass Scalar) Scalar {
+	var numerator, denominator Scalar
+	for i, vCurr := range p.vertices {
+		vNext := p.vertices[(i+1)%len(p.vertices)]
+		cross := abs(vCurr.Cross(vNext))
+		numerator += mul(cross, vCurr.Dot(vCurr)+vCurr.Dot(vNext)+vNext.Dot(vNext))
+		denominator += cross
+	}
+	return div(mul(mass, numerator), 6*denominator)
+}
+
+func (p *ConvexPolygon) RayCast(xf Transform, origin, direction Vec2, maxDistance Scalar) (Scalar, Vec2, bool) {
+	// Cast in local space so we don't have to move every vertex
+	localOrigin := origin.InverseTransform(xf)
+	localDirection := xf.InverseRotate(direction)
+	distance, normal, ok := rayCastPolygon(p.vertices, ZeroVec2(), localOrigin, localDirection, maxDistance)
+	if !ok {
+		return 0, Vec2{}, false
+	}
+	return distance, xf.Rotate(normal), true
+}
+
+///////////////////////////////////////////////////////////////////////
+
+// A point has no size, so it never collides with anything
+type Point struct{}
+
+func (p *Point) Type() BodyShape {
+	return PointMass
+}
+
+func (p *Point) Support(xf Transform, direction Vec2) Vec2 {
+	return xf.Pos
+}
+
+func (p *Point) AABB(xf Transform) (Vec2, Vec2) {
+	return xf.Pos, xf.Pos
+}
+
+func (p *Point) Area() Scalar {
+	return 0
+}
+
+func (p *Point) MomentOfInertia(mass Scalar) Scalar {
+	return 0
+}
+
+func (p *Point) RayCast(xf Transform, origin, direction Vec2, maxDistance Scalar) (Scalar, Vec2, bool) {
+	return 0, Vec2{}, false
+}
@@ -0,0 +1,23 @@
+//go:build !p2dfixed
+
+package physics2d
+
+import "testing"
+
+func TestConvexPolygonRejectsRepeatedVertices(t *testing.T) {
+	square := []Vec2{{0, 1}, {1, 1}, {1, 0}, {0, 0}}
+	if _, err := NewConvexPolygon(square); err != nil {
+		t.Fatalf("square was rejected: %v", err)
+	}
+
+	repeated := []Vec2{{0, 1}, {1, 1}, {1, 1}, {1, 0}, {0, 0}}
+	if _, err := NewConvexPolygon(repeated); err == nil {
+		t.Error("polygon with a repeated vertex was accepted")
+	}
+
+	// The last vertex wraps around to the first
+	closed := []Vec2{{0, 1}, {1, 1}, {1, 0}, {0, 0}, {0, 1}}
+	if _, err := NewConvexPolygon(closed); err == nil {
+		t.Error("polygon that repeats its first vertex at the end was accepted")
+	}
+}
@@ -0,0 +1,112 @@
+package physics2d
+
+type ShapeCastHit struct {
+	Body     *Body
+	Point    Vec2
+	Normal   Vec2   // Points out of the body that was hit, back towards the cast shape
+	Fraction Scalar // How much of the translation the shape can move before touching, from 0 to 1
+}
+
+// A cast stops when the shapes are this close (m), so the shape can always be moved to
+// the hit without overlapping anything
+const shapeCastTolerance = 1e-4
+
+// Each step moves the shape as far as it can go without touching, so only glancing hits
+// take more than a few. If it still hasn't got there, it stops where it is.
+const shapeCastIterations = 50
+
+// Sweeps a shape from where it currently is along the translation, and finds the first body
+// that it would hit. The shape doesn't have to be in the world, and if it is, it won't hit
+// itself. A shape that already overlaps something hits it at fraction 0, which is handy for
+// checking if something fits. Point masses don't collide, so they can't be cast or hit.
+func (w *World) ShapeCast(shape *Body, translation Vec2) (ShapeCastHit, bool) {
+	if shape.Shape() == PointMass {
+		return ShapeCastHit{}, false
+	}
+
+	// Bounds of the whole sweep, to skip bodies that are nowhere close
+	startMin, startMax := shape.AABB()
+	sweepMin := NewVec2(
+		min(startMin.x, startMin.x+translation.x),
+		min(startMin.y, startMin.y+translation.y),
+	)
+	sweepMax := NewVec2(
+		max(startMax.x, startMax.x+translation.x),
+		max(startMax.y, startMax.y+translation.y),
+	)
+
+	var closest ShapeCastHit
+	found := false
+	for _, b := range w.Bodies {
+		if b == shape || b.sensor || b.Shape() == PointMass || !shape.filter.CollidesWith(b.filter) {
+			continue
+		}
+		min, max := b.AABB()
+		if !aabbsOverlap(sweepMin, sweepMax, min, max) {
+			continue
+		}
+
+		hit, ok := shapeCastBody(shape, b, translation)
+		if ok && (!found || hit.Fraction < closest.Fraction) {
+			closest = hit
+			found = true
+		}
+	}
+	return closest, found
+}
+
+// Conservative advancement: the closest points give a direction that separates the
+// shapes, so the shape can move until the gap along it is (almost) gone without
+// touching anything. Then we look again from there.
+func shapeCastBody(shape, target *Body, translation Vec2) (ShapeCastHit, bool) {
+	moving := shape.clone()
+	start := shape.position
+
+	var t Scalar
+	for i := 0; ; i++ {
+		moving.MoveTo(start.Add(translation.ScaleMult(t)))
+		distance := GJKDistance(moving, target)
+		if distance.Overlapping || distance.Distance == 0 {
+			return overlapHit(moving, target, translation, t)
+		}
+
+		// From the shape towards the target
+		normal := distance.PointB.Sub(distance.PointA).ScaleDivide(distance.Distance)
+		approach := translation.Dot(normal)
+		if approach <= 0 {
+			// Sliding along or moving away, and for convex shapes the gap can't shrink after that
+			return ShapeCastHit{}, false
+		}
+		if distance.Distance <= fromFloat(shapeCastTolerance) || i == shapeCastIterations {
+			return ShapeCastHit{
+				Body:     target,
+				Point:    Midpoint(distance.PointA, distance.PointB),
+				Normal:   normal.ScaleMult(-one),
+				Fraction: t,
+			}, true
+		}
+
+		t += div(distance.Distance-fromFloat(shapeCastTolerance/2), approach)
+		if t > one {
+			return ShapeCastHit{}, false
+		}
+	}
+}
+
+// Only happens when the shape starts out overlapping or touching, so there's no gap to measure
+func overlapHit(moving, target *Body, translation Vec2, t Scalar) (ShapeCastHit, bool) {
+	penetration, ok := EPAPenetration(moving, target)
+	if !ok {
+		return ShapeCastHit{Body: target, Point: moving.position, Fraction: t}, true
+	}
+	// Resting on something and sliding along it (or lifting off) isn't a hit
+	if penetration.Depth <= fromFloat(shapeCastTolerance) && translation.Dot(penetration.Normal) <= 0 {
+		return ShapeCastHit{}, false
+	}
+	return ShapeCastHit{
+		Body:     target,
+		Point:    Midpoint(penetration.PointA, penetration.PointB),
+		Normal:   penetration.Normal.ScaleMult(-one),
+		Fraction: t,
+	}, true
+}
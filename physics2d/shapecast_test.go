@@ -0,0 +1,57 @@
+//go:build !p2dfixed
+
+package physics2d
+
+import (
+	"math"
+	"testing"
+)
+
+// A 2 m by 2 cm plank that's built at 45 degrees, so its bounding box is much
+// bigger than it is thick
+func diagonalPlank(position Vec2) *Body {
+	along := NewVec2(1, 1).Normalize()
+	across := NewVec2(1, -1).Normalize().ScaleMult(0.01)
+	return NewPolygon(position, []Vec2{
+		along.Add(across),
+		along.Sub(across),
+		along.ScaleMult(-1).Sub(across),
+		along.ScaleMult(-1).Add(across),
+	}, 0, 0, 1)
+}
+
+func TestShapeCastThinRotatedShapes(t *testing.T) {
+	target := diagonalPlank(NewVec2(5, 5))
+	moving := diagonalPlank(NewVec2(2, 8))
+	w := NewWorld([]*Body{target}, NewVec2(10, 10), 0, 1)
+
+	// Straight at the target, so only the planks' thickness is in the way
+	translation := NewVec2(6, -6)
+	hit, ok := w.ShapeCast(moving, translation)
+	if !ok {
+		t.Fatal("the plank went right through the other one")
+	}
+	gap := NewVec2(3, -3).Length() - 0.02
+	if want := gap / translation.Length(); math.Abs(hit.Fraction-want) > 1e-4 {
+		t.Errorf("hit at %v, want %v", hit.Fraction, want)
+	}
+	if want := NewVec2(-1, 1).Normalize(); !hit.Normal.CloseTo(want) {
+		t.Errorf("normal is %v, want %v", hit.Normal, want)
+	}
+
+	// Where it stopped has to be free
+	moving.Move(translation.ScaleMult(hit.Fraction))
+	if c, _ := Collide(moving, target); c != nil {
+		t.Error("the plank overlaps the target where the cast stopped")
+	}
+}
+
+func TestShapeCastMissesParallelShapes(t *testing.T) {
+	target := diagonalPlank(NewVec2(5, 5))
+	moving := diagonalPlank(NewVec2(2, 8))
+	w := NewWorld([]*Body{target}, NewVec2(10, 10), 0, 1)
+
+	if _, ok := w.ShapeCast(moving, NewVec2(1, 1)); ok {
+		t.Error("sliding alongside the target shouldn't hit it")
+	}
+}
@@ -0,0 +1,83 @@
+package physics2d
+
+import "slices"
+
+// A frozen copy of everything that changes while a world steps: every body's state,
+// the contact cache and the sensor overlaps. Restoring it puts the world back exactly
+// where it was, so stepping again gives the same results (see determinism.go).
+//
+// Settings like the Integrator, Forces, Listener and ShouldCollide aren't included.
+// There are no joints yet, so there's nothing to save for them.
+type Snapshot struct {
+	bodies         []bodySnapshot
+	contacts       []Collision
+	sensorOverlaps []bodyPair
+	paused         bool
+	nextID         uint64
+}
+
+// The body pointer is kept so that restoring writes into the same Body the game is
+// already holding, instead of handing it a new one
+type bodySnapshot struct {
+	body  *Body
+	state Body
+}
+
+// Cheap enough to call every tick. Bodies are copied by value, and the vertices they
+// share never change, so nothing else gets copied.
+func (w *World) Snapshot() *Snapshot {
+	w.assignIDs()
+	s := &Snapshot{
+		bodies:         make([]bodySnapshot, len(w.Bodies)),
+		contacts:       make([]Collision, len(w.contacts)),
+		sensorOverlaps: slices.Clone(w.sensorOverlaps),
+		paused:         w.Paused,
+		nextID:         w.nextID,
+	}
+	for i, b := range w.Bodies {
+		s.bodies[i] = bodySnapshot{b, *b}
+	}
+	for i, c := range w.contacts {
+		s.contacts[i] = *c
+		s.contacts[i].contactPoints = slices.Clone(c.contactPoints)
+	}
+	return s
+}
+
+// Rewinds the world to the snapshot. Bodies added since then are dropped and bodies
+// deleted since then come back. The snapshot isn't changed, so it can be restored again.
+func (w *World) Restore(s *Snapshot) {
+	// A new slice, so anyone still holding the old Bodies doesn't see it change under them
+	w.Bodies = make([]*Body, 0, len(s.bodies))
+	for _, bs := range s.bodies {
+		b := bs.body
+		buffer := b.transformedVertices
+		*b = bs.state
+		b.transformedVertices = buffer
+		b.needTransformUpdate = true
+		w.Bodies = append(w.Bodies, b)
+	}
+
+	w.contacts = w.contacts[:0]
+	if w.lastContactIndex == nil {
+		w.lastContactIndex = make(map[bodyPair]int, len(s.contacts))
+	}
+	clear(w.lastContactIndex)
+	for _, c := range s.contacts {
+		c.contactPoints = slices.Clone(c.contactPoints)
+		w.lastContactIndex[bodyPair{c.a, c.b}] = len(w.contacts)
+		w.contacts = append(w.contacts, &c)
+	}
+	w.sensorOverlaps = append(w.sensorOverlaps[:0], s.sensorOverlaps...)
+
+	// Events were about the tick that's being undone
+	w.CollisionEvents = w.CollisionEvents[:0]
+	w.SensorEvents = w.SensorEvents[:0]
+
+	w.Paused = s.paused
+	w.nextID = s.nextID
+}
+
+func (s *Snapshot) NumBodies() int {
+	return len(s.bodies)
+}
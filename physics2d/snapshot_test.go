@@ -0,0 +1,33 @@
+//go:build !p2dfixed
+
+package physics2d
+
+import "testing"
+
+// Only NewWorld makes the contact maps, so a zero World has to make them itself
+func TestZeroWorldContacts(t *testing.T) {
+	ground := NewBox(NewVec2(5, 0.5), NewVec2(10, 1), 0, 0, 0)
+	ball := NewBall(NewVec2(5, 1.4), 0.5, 0, 1)
+	w := NewWorld([]*Body{ground, ball}, NewVec2(10, 10), 9.8, 4)
+	w.UpdatePhysics(1.0 / 60)
+	if len(w.contacts) == 0 {
+		t.Fatal("the ball should be touching the ground")
+	}
+
+	var restored World
+	restored.Restore(w.Snapshot())
+	if len(restored.contacts) != len(w.contacts) {
+		t.Fatal("contacts weren't restored")
+	}
+
+	var empty World
+	c, err := Collide(ground, ball)
+	if err != nil || c == nil {
+		t.Fatal("the ball should be touching the ground")
+	}
+	empty.addContact(c)
+	i, ok := empty.contactIndex[bodyPair{c.a, c.b}]
+	if !ok || empty.contactBuffer[i] != c {
+		t.Error("the contact can't be looked up after adding it")
+	}
+}
@@ -0,0 +1,334 @@
+package physics2d
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// The parts of a body that change as the world steps. Everything else (shape, mass,
+// friction...) is set up once, so it goes in a scene file instead (see scene.go).
+type BodyState struct {
+	ID                 uint64 `json:"id"`
+	Position           Vec2   `json:"position"`
+	Velocity           Vec2   `json:"velocity"`
+	Rotation           Scalar `json:"rotation"`
+	RotationalVelocity Scalar `json:"rotationalVelocity"`
+}
+
+// Where every body is on one tick, sorted by ID
+type State struct {
+	Bodies []BodyState `json:"bodies"`
+}
+
+func (w *World) State() State {
+	w.assignIDs()
+	s := State{make([]BodyState, len(w.Bodies))}
+	for i, b := range w.Bodies {
+		s.Bodies[i] = BodyState{b.id, b.position, b.velocity, b.rotation, b.rotationalVelocity}
+	}
+	slices.SortFunc(s.Bodies, compareBodyStates)
+	return s
+}
+
+// Moves the world's bodies to where they are in the state. Bodies that aren't in
+// the state are left alone, but every body in the state has to be in the world.
+func (w *World) ApplyState(s State) error {
+	w.assignIDs()
+	byID := make(map[uint64]*Body, len(w.Bodies))
+	for _, b := range w.Bodies {
+		byID[b.id] = b
+	}
+	for _, bs := range s.Bodies {
+		b, ok := byID[bs.ID]
+		if !ok {
+			return fmt.Errorf("physics2d: no body with id %d", bs.ID)
+		}
+		b.MoveTo(bs.Position)
+		b.RotateTo(bs.Rotation)
+		// Jump straight there, otherwise interpolation slides the body over from where it was
+		b.previousPosition = bs.Position
+		b.previousRotation = bs.Rotation
+		b.velocity = bs.Velocity
+		b.rotationalVelocity = bs.RotationalVelocity
+	}
+	return nil
+}
+
+func compareBodyStates(a, b BodyState) int {
+	if a.ID < b.ID {
+		return -1
+	}
+	if a.ID > b.ID {
+		return 1
+	}
+	return 0
+}
+
+// Binary format
+//
+// Everything is fixed width and little-endian. Numbers are stored as their full 64 bits
+// (float64, or the raw Fixed with -tags p2dfixed), so decoding gives back exactly the same
+// state (which determinism needs). States from one build can't be read by the other.
+//
+//	magic      [4]byte  "P2DS"
+//	version    uint8
+//	flags      uint8    1 if this is a delta
+//	count      uint32   number of bodies that follow
+//	removed    uint32   delta only, then that many uint64 ids of bodies that are gone
+//	bodies     count of:
+//	  id       uint64
+//	  changed  uint8    delta only, which fields follow (see the field bits)
+//	  fields   position (2 uint64), velocity (2 uint64), rotation, rotational velocity
+//
+// A full frame is 10 bytes plus 56 bytes per body. Deltas leave out bodies and fields that
+// are bit-for-bit the same as the previous frame, so resting bodies cost nothing.
+
+// Bump this whenever the format changes
+const StateFormatVersion = 1
+
+var stateMagic = [4]byte{'P', '2', 'D', 'S'}
+
+const stateFlagDelta = 1
+
+// Which fields are in a delta
+const (
+	fieldPosition = 1 << iota
+	fieldVelocity
+	fieldRotation
+	fieldRotationalVelocity
+
+	allFields = fieldPosition | fieldVelocity | fieldRotation | fieldRotationalVelocity
+)
+
+// Appends the state to buf. If prev isn't nil, only what changed since prev is written,
+// and the same prev has to be passed to DecodeState to read it back.
+func AppendState(buf []byte, s State, prev *State) []byte {
+	buf = append(buf, stateMagic[:]...)
+	buf = append(buf, StateFormatVersion)
+	if prev == nil {
+		buf = append(buf, 0)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(s.Bodies)))
+		for _, bs := range s.Bodies {
+			buf = binary.LittleEndian.AppendUint64(buf, bs.ID)
+			buf = appendBodyFields(buf, bs, allFields)
+		}
+		return buf
+	}
+
+	buf = append(buf, stateFlagDelta)
+	var removed []uint64
+	var changed []BodyState
+	var masks []byte
+	// Both are sorted by ID, so walk them together
+	i := 0
+	for _, bs := range s.Bodies {
+		for i < len(prev.Bodies) && prev.Bodies[i].ID < bs.ID {
+			removed = append(removed, prev.Bodies[i].ID)
+			i++
+		}
+		mask := byte(allFields)
+		if i < len(prev.Bodies) && prev.Bodies[i].ID == bs.ID {
+			mask = changedFields(prev.Bodies[i], bs)
+			i++
+		}
+		if mask != 0 {
+			changed = append(changed, bs)
+			masks = append(masks, mask)
+		}
+	}
+	for ; i < len(prev.Bodies); i++ {
+		removed = append(removed, prev.Bodies[i].ID)
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(changed)))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(removed)))
+	for _, id := range removed {
+		buf = binary.LittleEndian.AppendUint64(buf, id)
+	}
+	for j, bs := range changed {
+		buf = binary.LittleEndian.AppendUint64(buf, bs.ID)
+		buf = append(buf, masks[j])
+		buf = appendBodyFields(buf, bs, masks[j])
+	}
+	return buf
+}
+
+// Compares bits instead of values, so that -0 and NaN changes aren't lost
+func changedFields(a, b BodyState) byte {
+	same := func(x, y Scalar) bool {
+		return scalarBits(x) == scalarBits(y)
+	}
+	var mask byte
+	if !same(a.Position.x, b.Position.x) || !same(a.Position.y, b.Position.y) {
+		mask |= fieldPosition
+	}
+	if !same(a.Velocity.x, b.Velocity.x) || !same(a.Velocity.y, b.Velocity.y) {
+		mask |= fieldVelocity
+	}
+	if !same(a.Rotation, b.Rotation) {
+		mask |= fieldRotation
+	}
+	if !same(a.RotationalVelocity, b.RotationalVelocity) {
+		mask |= fieldRotationalVelocity
+	}
+	return mask
+}
+
+func appendBodyFields(buf []byte, bs BodyState, mask byte) []byte {
+	putFloat := func(f Scalar) {
+		buf = binary.LittleEndian.AppendUint64(buf, scalarBits(f))
+	}
+	if mask&fieldPosition != 0 {
+		putFloat(bs.Position.x)
+		putFloat(bs.Position.y)
+	}
+	if mask&fieldVelocity != 0 {
+		putFloat(bs.Velocity.x)
+		putFloat(bs.Velocity.y)
+	}
+	if mask&fieldRotation != 0 {
+		putFloat(bs.Rotation)
+	}
+	if mask&fieldRotationalVelocity != 0 {
+		putFloat(bs.RotationalVelocity)
+	}
+	return buf
+}
+
+var errStateTooShort = errors.New("physics2d: state data is too short")
+
+// Reads a state written by AppendState. Deltas need the same prev that they were written against.
+func DecodeState(data []byte, prev *State) (State, error) {
+	if len(data) < 10 {
+		return State{}, errStateTooShort
+	}
+	if [4]byte(data[:4]) != stateMagic {
+		return State{}, errors.New("physics2d: not a state")
+	}
+	if data[4] != StateFormatVersion {
+		return State{}, fmt.Errorf("physics2d: unsupported state version %d", data[4])
+	}
+	delta := data[5]&stateFlagDelta != 0
+	count := binary.LittleEndian.Uint32(data[6:])
+	data = data[10:]
+
+	readUint64 := func() (uint64, bool) {
+		if len(data) < 8 {
+			return 0, false
+		}
+		v := binary.LittleEndian.Uint64(data)
+		data = data[8:]
+		return v, true
+	}
+	readFloat := func(f *Scalar) bool {
+		v, ok := readUint64()
+		*f = scalarFromBits(v)
+		return ok
+	}
+
+	var bodies []BodyState
+	if delta {
+		if prev == nil {
+			return State{}, errors.New("physics2d: delta state needs the previous state")
+		}
+		if len(data) < 4 {
+			return State{}, errStateTooShort
+		}
+		numRemoved := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		// The counts come from the frame, so check them before allocating anything
+		if uint64(numRemoved)*8 > uint64(len(data)) {
+			return State{}, errStateTooShort
+		}
+		removed := make(map[uint64]bool, numRemoved)
+		for range numRemoved {
+			id, ok := readUint64()
+			if !ok {
+				return State{}, errStateTooShort
+			}
+			removed[id] = true
+		}
+		for _, bs := range prev.Bodies {
+			if !removed[bs.ID] {
+				bodies = append(bodies, bs)
+			}
+		}
+	}
+
+	// Every body has at least its ID, and a mask in deltas
+	minBodySize := uint64(8)
+	if delta {
+		minBodySize++
+	} else {
+		minBodySize += 6 * 8
+	}
+	if uint64(count)*minBodySize > uint64(len(data)) {
+		return State{}, errStateTooShort
+	}
+	if !delta {
+		bodies = make([]BodyState, 0, count)
+	}
+
+	for range count {
+		var bs BodyState
+		var ok bool
+		if bs.ID, ok = readUint64(); !ok {
+			return State{}, errStateTooShort
+		}
+		mask := byte(allFields)
+		if delta {
+			if len(data) < 1 {
+				return State{}, errStateTooShort
+			}
+			mask = data[0]
+			data = data[1:]
+		}
+
+		i, found := slices.BinarySearchFunc(bodies, bs, compareBodyStates)
+		if found {
+			bs = bodies[i]
+		} else if mask != allFields {
+			return State{}, fmt.Errorf("physics2d: delta is missing fields for new body %d", bs.ID)
+		}
+
+		ok = true
+		if mask&fieldPosition != 0 {
+			ok = ok && readFloat(&bs.Position.x) && readFloat(&bs.Position.y)
+		}
+		if mask&fieldVelocity != 0 {
+			ok = ok && readFloat(&bs.Velocity.x) && readFloat(&bs.Velocity.y)
+		}
+		if mask&fieldRotation != 0 {
+			ok = ok && readFloat(&bs.Rotation)
+		}
+		if mask&fieldRotationalVelocity != 0 {
+			ok = ok && readFloat(&bs.RotationalVelocity)
+		}
+		if !ok {
+			return State{}, errStateTooShort
+		}
+
+		if found {
+			bodies[i] = bs
+		} else {
+			bodies = slices.Insert(bodies, i, bs)
+		}
+	}
+	return State{bodies}, nil
+}
+
+// Full frames only, use AppendState and DecodeState for deltas
+func (s State) MarshalBinary() ([]byte, error) {
+	return AppendState(nil, s, nil), nil
+}
+
+func (s *State) UnmarshalBinary(data []byte) error {
+	decoded, err := DecodeState(data, nil)
+	if err != nil {
+		return err
+	}
+	*s = decoded
+	return nil
+}
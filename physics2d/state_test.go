@@ -0,0 +1,86 @@
+//go:build !p2dfixed
+
+package physics2d
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestStateDeltaRoundTrip(t *testing.T) {
+	w := testWorld(t)
+	var prev *State
+	for step := range 120 {
+		if step == 60 {
+			w.DeleteBody(4)
+		}
+		w.UpdatePhysics(1.0 / 60)
+		s := w.State()
+		decoded, err := DecodeState(AppendState(nil, s, prev), prev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded.Bodies) != len(s.Bodies) {
+			t.Fatalf("step %d: decoded %d bodies instead of %d", step, len(decoded.Bodies), len(s.Bodies))
+		}
+		for i := range s.Bodies {
+			if decoded.Bodies[i] != s.Bodies[i] {
+				t.Fatalf("step %d: body %d decoded differently", step, s.Bodies[i].ID)
+			}
+		}
+		prev = &decoded
+	}
+}
+
+// Applying a state teleports the bodies, so there's nothing to interpolate from
+func TestApplyStateResetsInterpolation(t *testing.T) {
+	w := testWorld(t)
+	for range 30 {
+		w.UpdatePhysics(1.0 / 60)
+	}
+	s := w.State()
+	for range 30 {
+		w.UpdatePhysics(1.0 / 60)
+	}
+	if err := w.ApplyState(s); err != nil {
+		t.Fatal(err)
+	}
+	for i, bs := range s.Bodies {
+		b := w.Bodies[i]
+		if b.ID() != bs.ID {
+			t.Fatalf("body %d is where body %d should be", b.ID(), bs.ID)
+		}
+		for _, alpha := range []float64{0, 0.5} {
+			if p := b.InterpolatedPosition(alpha); p != bs.Position {
+				t.Errorf("body %d: interpolated to %v at alpha %v instead of %v", bs.ID, p, alpha, bs.Position)
+			}
+			if r := b.InterpolatedRotation(alpha); r != bs.Rotation {
+				t.Errorf("body %d: interpolated to %v rad at alpha %v instead of %v", bs.ID, r, alpha, bs.Rotation)
+			}
+		}
+	}
+}
+
+// Counts that are bigger than the frame shouldn't get anything allocated for them
+func TestDecodeStateRejectsHugeCounts(t *testing.T) {
+	header := func(flags byte, count uint32) []byte {
+		buf := append(stateMagic[:], StateFormatVersion, flags)
+		return binary.LittleEndian.AppendUint32(buf, count)
+	}
+	frames := map[string][]byte{
+		"full":    header(0, 0xFFFFFFFF),
+		"delta":   header(stateFlagDelta, 0xFFFFFFFF),
+		"removed": binary.LittleEndian.AppendUint32(header(stateFlagDelta, 0), 0x08000000),
+	}
+	prev := &State{}
+	for name, frame := range frames {
+		allocs := testing.AllocsPerRun(1, func() {
+			if _, err := DecodeState(frame, prev); err != errStateTooShort {
+				t.Errorf("%s: got %v", name, err)
+			}
+		})
+		if allocs > 2 {
+			t.Errorf("%s: %v allocations", name, allocs)
+		}
+	}
+}
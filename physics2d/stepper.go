@@ -0,0 +1,66 @@
+package physics2d
+
+import "errors"
+
+// Frame times jump around, but physics behaves best with a fixed time step. The
+// stepper saves up real time and spends it in fixed sized chunks. Whatever is left
+// over becomes the interpolation alpha, so rendering can blend between the last
+// two steps instead of stuttering.
+type Stepper struct {
+	World     *World
+	FixedStep Scalar // s, has to be positive
+
+	// If the physics can't keep up, every frame takes longer and needs even more steps
+	// (the spiral of death). We stop that by never catching up on more than this.
+	MaxFrameTime Scalar // s
+	MaxSteps     int    // Has to be at least 1
+
+	accumulator Scalar
+}
+
+func NewStepper(world *World, fixedStep Scalar) (*Stepper, error) {
+	if fixedStep <= 0 {
+		return nil, errors.New("physics2d: fixed step must be positive")
+	}
+	return &Stepper{
+		World:        world,
+		FixedStep:    fixedStep,
+		MaxFrameTime: fromFloat(0.25),
+		MaxSteps:     8,
+		accumulator:  0,
+	}, nil
+}
+
+// Call this every frame with the real time since the last frame. Returns how many
+// fixed steps were run.
+func (s *Stepper) Advance(frameTime Scalar) int {
+	// The fields can be changed after NewStepper, and a zero step would never use up the time
+	if s.FixedStep <= 0 || s.MaxSteps <= 0 {
+		return 0
+	}
+	if frameTime > s.MaxFrameTime {
+		frameTime = s.MaxFrameTime
+	}
+	s.accumulator += frameTime
+
+	steps := 0
+	for s.accumulator >= s.FixedStep {
+		if steps == s.MaxSteps {
+			// Too far behind, let the time go instead of trying to catch up
+			s.accumulator = 0
+			break
+		}
+		s.World.UpdatePhysics(s.FixedStep)
+		s.accumulator -= s.FixedStep
+		steps++
+	}
+	return steps
+}
+
+// How far we are between the last step and the next one, from 0 to 1
+func (s *Stepper) Alpha() Scalar {
+	if s.FixedStep <= 0 {
+		return 0
+	}
+	return div(s.accumulator, s.FixedStep)
+}
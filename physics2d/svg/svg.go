@@ -0,0 +1,257 @@
+// Package svg exports a physics2d world as an SVG drawing, with optional traces of
+// where each body has been, velocity arrows and contact normals. Coordinates are
+// rounded to 0.01 px and everything is written in the same order every time, so
+// the output of two runs can be diffed as text.
+package svg
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+	"github.com/lwbuchanan/Physics2D/physics2d/raster"
+)
+
+var (
+	VelocityColor = color.RGBA{230, 41, 55, 255}
+	ContactColor  = color.RGBA{0, 228, 48, 255}
+)
+
+type Options struct {
+	PixelsPerMeter float64
+	Background     *color.RGBA // Transparent if nil
+
+	// Picks the color of each body. Uses physics2d.DebugBodyColor if nil.
+	BodyColor func(b *p2d.Body) color.RGBA
+
+	// Arrows from each moving body's position to position + velocity, like the demo draws
+	Velocities bool
+
+	// Contact points and normals from the last step
+	Contacts bool
+
+	// Paths that bodies took, drawn under everything else. Can be nil.
+	Traces *Tracer
+}
+
+func DefaultOptions() Options {
+	background := raster.Background
+	return Options{
+		PixelsPerMeter: 200,
+		Background:     &background,
+		Velocities:     true,
+		Contacts:       true,
+	}
+}
+
+// Remembers where every body has been. Call Record after each step you want in the path.
+type Tracer struct {
+	paths map[uint64][]p2d.Vec2
+}
+
+func NewTracer() *Tracer {
+	return &Tracer{make(map[uint64][]p2d.Vec2)}
+}
+
+// Static bodies never go anywhere, so they're skipped
+func (t *Tracer) Record(world *p2d.World) {
+	for _, b := range world.Bodies {
+		if b.Mass() == 0 {
+			continue
+		}
+		t.paths[b.ID()] = append(t.paths[b.ID()], b.Position())
+	}
+}
+
+func (t *Tracer) Path(id uint64) []p2d.Vec2 {
+	return t.paths[id]
+}
+
+func Write(w io.Writer, world *p2d.World, opts Options) error {
+	out := bufio.NewWriter(w)
+	e := encoder{out, opts.PixelsPerMeter, world.Dimensions().Y()}
+	bodyColor := opts.BodyColor
+	if bodyColor == nil {
+		bodyColor = p2d.DebugBodyColor
+	}
+
+	dimensions := world.Dimensions()
+	width := e.num(dimensions.X() * e.scale)
+	height := e.num(dimensions.Y() * e.scale)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		width, height, width, height)
+
+	// One arrowhead per color, since markers can't pick up the line's color everywhere
+	out.WriteString("<defs>\n")
+	for _, c := range []color.RGBA{VelocityColor, ContactColor} {
+		fmt.Fprintf(out, `<marker id="arrow-%s" viewBox="0 0 10 10" refX="9" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse">`+
+			`<path d="M0,0 L10,5 L0,10 z" %s/></marker>`+"\n", hex(c)[1:], paint("fill", c, 1))
+	}
+	out.WriteString("</defs>\n")
+
+	if opts.Background != nil {
+		fmt.Fprintf(out, `<rect width="100%%" height="100%%" %s/>`+"\n", paint("fill", *opts.Background, 1))
+	}
+
+	if opts.Traces != nil {
+		out.WriteString(`<g id="traces" fill="none" stroke-width="1.5">` + "\n")
+		ids := make([]uint64, 0, len(opts.Traces.paths))
+		for id := range opts.Traces.paths {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+
+		colors := make(map[uint64]color.RGBA, len(world.Bodies))
+		for _, b := range world.Bodies {
+			colors[b.ID()] = bodyColor(b)
+		}
+		for _, id := range ids {
+			// Bodies that are gone by now get a neutral color
+			c, ok := colors[id]
+			if !ok {
+				c = p2d.DebugStaticColor
+			}
+			fmt.Fprintf(out, `<polyline id="trace-%d" %s points="%s"/>`+"\n",
+				id, paint("stroke", c, traceOpacity), e.points(opts.Traces.paths[id]))
+		}
+		out.WriteString("</g>\n")
+	}
+
+	out.WriteString(`<g id="bodies">` + "\n")
+	for _, b := range world.Bodies {
+		c := paint("fill", bodyColor(b), 1)
+		switch b.Shape() {
+		case p2d.Ball:
+			x, y := e.point(b.Position())
+			fmt.Fprintf(out, `<circle id="body-%d" cx="%s" cy="%s" r="%s" %s/>`+"\n",
+				b.ID(), x, y, e.num(b.Radius()*e.scale), c)
+		case p2d.PointMass:
+			e.dot(b, c)
+		case p2d.Polygon:
+			e.polygon(b, b.Vertices(), c)
+		default:
+			// Custom shapes get traced like DebugDraw does
+			e.polygon(b, p2d.SupportOutline(b.Geometry(), b.Transform()), c)
+		}
+	}
+	out.WriteString("</g>\n")
+
+	if opts.Velocities {
+		out.WriteString(`<g id="velocities">` + "\n")
+		for _, b := range world.Bodies {
+			if b.Mass() == 0 || b.Velocity().LengthSquared() == 0 {
+				continue
+			}
+			e.arrow(b.Position(), b.Position().Add(b.Velocity()), VelocityColor)
+		}
+		out.WriteString("</g>\n")
+	}
+
+	if opts.Contacts {
+		out.WriteString(`<g id="contacts">` + "\n")
+		for _, collision := range world.CollisionEvents {
+			for _, cp := range collision.ContactPoints() {
+				x, y := e.point(cp)
+				fmt.Fprintf(out, `<circle cx="%s" cy="%s" r="3" %s/>`+"\n", x, y, paint("fill", ContactColor, 1))
+				e.arrow(cp, cp.Add(collision.Normal().ScaleMult(0.2)), ContactColor)
+			}
+		}
+		out.WriteString("</g>\n")
+	}
+
+	out.WriteString("</svg>\n")
+	return out.Flush()
+}
+
+func WriteFile(path string, world *p2d.World, opts Options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, world, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+type encoder struct {
+	out         *bufio.Writer
+	scale       float64 // px/m
+	worldHeight float64 // m
+}
+
+// Same transform as toRLVec in the demo
+func (e encoder) point(v p2d.Vec2) (string, string) {
+	return e.num(v.X() * e.scale), e.num((e.worldHeight - v.Y()) * e.scale)
+}
+
+func (e encoder) points(vs []p2d.Vec2) string {
+	var sb strings.Builder
+	for i, v := range vs {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		x, y := e.point(v)
+		sb.WriteString(x)
+		sb.WriteByte(',')
+		sb.WriteString(y)
+	}
+	return sb.String()
+}
+
+func (e encoder) num(f float64) string {
+	f = math.Round(f*100) / 100
+	if f == 0 {
+		// Don't write -0
+		f = 0
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (e encoder) dot(b *p2d.Body, fill string) {
+	x, y := e.point(b.Position())
+	fmt.Fprintf(e.out, `<circle id="body-%d" cx="%s" cy="%s" r="2" %s/>`+"\n", b.ID(), x, y, fill)
+}
+
+// Outlines with less than 3 points have no area, so they're drawn as a dot instead
+func (e encoder) polygon(b *p2d.Body, vertices []p2d.Vec2, fill string) {
+	if len(vertices) < 3 {
+		e.dot(b, fill)
+		return
+	}
+	fmt.Fprintf(e.out, `<polygon id="body-%d" points="%s" %s/>`+"\n", b.ID(), e.points(vertices), fill)
+}
+
+func (e encoder) arrow(from, to p2d.Vec2, c color.RGBA) {
+	x1, y1 := e.point(from)
+	x2, y2 := e.point(to)
+	fmt.Fprintf(e.out, `<line x1="%s" y1="%s" x2="%s" y2="%s" %s stroke-width="2" marker-end="url(#arrow-%s)"/>`+"\n",
+		x1, y1, x2, y2, paint("stroke", c, 1), hex(c)[1:])
+}
+
+// Traces are drawn fainter than the bodies they follow
+const traceOpacity = 0.6
+
+// The colors are premultiplied and SVG's aren't, so undo that for the hex value
+func hex(c color.RGBA) string {
+	straight := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", straight.R, straight.G, straight.B)
+}
+
+// Writes fill="#rrggbb" (or stroke), plus an opacity when the color isn't opaque
+func paint(attr string, c color.RGBA, opacity float64) string {
+	opacity *= float64(c.A) / 255
+	if opacity >= 1 {
+		return fmt.Sprintf(`%s="%s"`, attr, hex(c))
+	}
+	opacity = math.Round(opacity*1000) / 1000
+	return fmt.Sprintf(`%s="%s" %s-opacity="%s"`, attr, hex(c), attr, strconv.FormatFloat(opacity, 'f', -1, 64))
+}
@@ -0,0 +1,49 @@
+package svg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	p2d "github.com/lwbuchanan/Physics2D/physics2d"
+)
+
+var diamondType = p2d.NewShapeType()
+
+// A custom shape that the renderer has never heard of
+type diamond struct {
+	*p2d.ConvexPolygon
+}
+
+func (d diamond) Type() p2d.BodyShape {
+	return diamondType
+}
+
+func TestWriteCustomShapesAndAlpha(t *testing.T) {
+	polygon, err := p2d.NewConvexPolygon([]p2d.Vec2{
+		p2d.NewVec2(0, 0.5), p2d.NewVec2(0.5, 0), p2d.NewVec2(0, -0.5), p2d.NewVec2(-0.5, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom, err := p2d.NewBody(diamond{polygon}, p2d.NewVec2(2, 2), 0, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sensor := p2d.NewBall(p2d.NewVec2(4, 2), 0.5, 0, 0)
+	sensor.SetSensor(true)
+	world := p2d.NewWorld([]*p2d.Body{custom, sensor}, p2d.NewVec2(6, 4), 0, 1)
+
+	var out bytes.Buffer
+	if err := Write(&out, &world, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	svg := out.String()
+	if !strings.Contains(svg, `<polygon id="body-1" points="`) {
+		t.Error("the custom shape wasn't drawn")
+	}
+	// DebugSensorColor un-premultiplied (89 at 100/255 is 227), at 100/255
+	if !strings.Contains(svg, `fill="#00e330" fill-opacity="0.392"`) {
+		t.Errorf("the sensor lost its alpha:\n%s", svg)
+	}
+}
@@ -1,21 +1,33 @@
 package physics2d
 
-import "math"
-
-type transform struct {
+// A rotation followed by a translation
+type Transform struct {
 	Pos Vec2
-	Sin float64
-	Cos float64
+	Sin Scalar
+	Cos Scalar
 }
 
-//func zeroTransform() transform {
-//	return newTransform(ZeroVec2(), 0)
+//func zeroTransform() Transform {
+//	return NewTransform(ZeroVec2(), 0)
 //}
 
-func newTransform(pos Vec2, angle float64) transform {
-	return transform{
+func NewTransform(pos Vec2, angle Scalar) Transform {
+	return Transform{
 		Pos: pos,
-		Sin: math.Sin(angle),
-		Cos: math.Cos(angle),
+		Sin: sin(angle),
+		Cos: cos(angle),
 	}
 }
+
+func (t Transform) Angle() Scalar {
+	return atan2(t.Sin, t.Cos)
+}
+
+// Only the rotation part, for directions that shouldn't be moved
+func (t Transform) Rotate(v Vec2) Vec2 {
+	return Vec2{mul(v.x, t.Cos) - mul(v.y, t.Sin), mul(v.x, t.Sin) + mul(v.y, t.Cos)}
+}
+
+func (t Transform) InverseRotate(v Vec2) Vec2 {
+	return Vec2{mul(v.x, t.Cos) + mul(v.y, t.Sin), mul(-v.x, t.Sin) + mul(v.y, t.Cos)}
+}
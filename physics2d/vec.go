@@ -1,27 +1,27 @@
 package physics2d
 
 import (
-	"math"
+	"encoding/json"
 	"slices"
 )
 
 type Vec2 struct {
-	x, y float64
+	x, y Scalar
 }
 
 func ZeroVec2() Vec2 {
 	return Vec2{0, 0}
 }
 
-func NewVec2(x, y float64) Vec2 {
+func NewVec2(x, y Scalar) Vec2 {
 	return Vec2{x, y}
 }
 
-func (v Vec2) X() float64 {
+func (v Vec2) X() Scalar {
 	return v.x
 }
 
-func (v Vec2) Y() float64 {
+func (v Vec2) Y() Scalar {
 	return v.y
 }
 
@@ -33,53 +33,59 @@ func (v1 Vec2) Sub(v2 Vec2) Vec2 {
 	return Vec2{v1.x - v2.x, v1.y - v2.y}
 }
 
-func (v1 Vec2) ScaleMult(scalar float64) Vec2 {
-	return Vec2{v1.x * scalar, v1.y * scalar}
+func (v1 Vec2) ScaleMult(scalar Scalar) Vec2 {
+	return Vec2{mul(v1.x, scalar), mul(v1.y, scalar)}
 }
 
-func (v1 Vec2) ScaleDivide(scalar float64) Vec2 {
-	return Vec2{v1.x / scalar, v1.y / scalar}
+func (v1 Vec2) ScaleDivide(scalar Scalar) Vec2 {
+	return Vec2{div(v1.x, scalar), div(v1.y, scalar)}
 }
 
-func (v1 Vec2) Dot(v2 Vec2) float64 {
-	return v1.x*v2.x + v1.y*v2.y
+func (v1 Vec2) Dot(v2 Vec2) Scalar {
+	return mul(v1.x, v2.x) + mul(v1.y, v2.y)
 }
 
 // Z component of the cross product
-func (v1 Vec2) Cross(v2 Vec2) float64 {
-	return v1.x*v2.y - v1.y*v2.x
+func (v1 Vec2) Cross(v2 Vec2) Scalar {
+	return mul(v1.x, v2.y) - mul(v1.y, v2.x)
 }
 
-func (v Vec2) Transform(t transform) Vec2 {
-	rx := v.x*t.Cos - v.y*t.Sin
-	ry := v.x*t.Sin + v.y*t.Cos
+func (v Vec2) Transform(t Transform) Vec2 {
+	rx := mul(v.x, t.Cos) - mul(v.y, t.Sin)
+	ry := mul(v.x, t.Sin) + mul(v.y, t.Cos)
 	// Rotate THEN translate
 	return Vec2{rx, ry}.Add(t.Pos)
 }
 
+// Takes a world space point back into the transform's local space
+func (v Vec2) InverseTransform(t Transform) Vec2 {
+	// Untranslate THEN unrotate
+	return t.InverseRotate(v.Sub(t.Pos))
+}
+
 // True if v1 and v2 are within 0.00025
 func (v1 Vec2) CloseTo(v2 Vec2) bool {
-	return v1.DistanceSquared(v2) < 0.00025
+	return v1.DistanceSquared(v2) < fromFloat(0.00025)
 }
 
-func (v Vec2) LengthSquared() float64 {
-	return v.x*v.x + v.y*v.y
+func (v Vec2) LengthSquared() Scalar {
+	return mul(v.x, v.x) + mul(v.y, v.y)
 }
 
-func (v1 Vec2) DistanceSquared(v2 Vec2) float64 {
+func (v1 Vec2) DistanceSquared(v2 Vec2) Scalar {
 	xDist := v2.x - v1.x
 	yDist := v2.y - v1.y
-	return xDist*xDist + yDist*yDist
+	return mul(xDist, xDist) + mul(yDist, yDist)
 }
 
 // Uses sqrt, use DistanceSquared if possible
-func (v1 Vec2) Distance(v2 Vec2) float64 {
-	return math.Sqrt(v1.DistanceSquared(v2))
+func (v1 Vec2) Distance(v2 Vec2) Scalar {
+	return sqrt(v1.DistanceSquared(v2))
 }
 
 // Uses sqrt, use LengthSquared if possible
-func (v Vec2) Length() float64 {
-	return math.Sqrt(v.LengthSquared())
+func (v Vec2) Length() Scalar {
+	return sqrt(v.LengthSquared())
 }
 
 // Uses sqrt, avoid if possible
@@ -115,18 +121,32 @@ func cmpY(a, b Vec2) int {
 	return 0
 }
 
-func MinX(vecs []Vec2) float64 {
+func MinX(vecs []Vec2) Scalar {
 	return slices.MinFunc(vecs, cmpX).x
 }
 
-func MinY(vecs []Vec2) float64 {
+func MinY(vecs []Vec2) Scalar {
 	return slices.MinFunc(vecs, cmpY).y
 }
 
-func MaxX(vecs []Vec2) float64 {
+func MaxX(vecs []Vec2) Scalar {
 	return slices.MaxFunc(vecs, cmpX).x
 }
 
-func MaxY(vecs []Vec2) float64 {
+func MaxY(vecs []Vec2) Scalar {
 	return slices.MaxFunc(vecs, cmpY).y
 }
+
+// Vectors are saved as [x, y], in meters whatever Scalar is, so files work with both builds
+func (v Vec2) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]float64{toFloat(v.x), toFloat(v.y)})
+}
+
+func (v *Vec2) UnmarshalJSON(data []byte) error {
+	var xy [2]float64
+	if err := json.Unmarshal(data, &xy); err != nil {
+		return err
+	}
+	v.x, v.y = fromFloat(xy[0]), fromFloat(xy[1])
+	return nil
+}
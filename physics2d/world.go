@@ -13,41 +13,92 @@ import (
 type World struct {
 	Bodies          []*Body
 	dimensions      Vec2
-	gravity         float64 // m/s/s
+	gravity         Scalar // m/s/s
 	timeSteps       int
 	collisionBuffer []*Collision
 	CollisionEvents []*Collision
+	SensorEvents    []SensorEvent
+	sensorOverlaps  []bodyPair
+	sensorBuffer    []bodyPair
 	Paused          bool
+
+	// Gets told about contacts as they begin, get solved and end. Can be nil.
+	Listener         ContactListener
+	contacts         []*Collision
+	contactBuffer    []*Collision
+	lastContactIndex map[bodyPair]int
+	contactIndex     map[bodyPair]int
+
+	// Optional extra filtering on top of each body's Filter. Return false to
+	// stop a pair from colliding.
+	ShouldCollide func(a, b *Body) bool
+
+	// Moves bodies forward every time step. Semi-implicit Euler if nil.
+	Integrator Integrator
+
+	// Extra forces that get applied to every body with mass, on top of gravity
+	Forces []ForceFunc
+
+	// See determinism.go
+	Deterministic bool
+	nextID        uint64
+
+	// A Recorder or Replayer, see replay.go
+	hook stepHook
 }
 
-func NewWorld(bodies []*Body, dimensions Vec2, gravity float64, timeSteps int) World {
-	return World{
+func NewWorld(bodies []*Body, dimensions Vec2, gravity Scalar, timeSteps int) World {
+	w := World{
 		Bodies:          bodies,
 		dimensions:      dimensions,
 		gravity:         gravity,
 		timeSteps:       timeSteps,
-		collisionBuffer: make([]*Collision, len(bodies)),
-		CollisionEvents: make([]*Collision, len(bodies)),
+		collisionBuffer: make([]*Collision, 0, len(bodies)),
+		CollisionEvents: make([]*Collision, 0, len(bodies)),
 		Paused:          false,
+
+		lastContactIndex: make(map[bodyPair]int),
+		contactIndex:     make(map[bodyPair]int),
+	}
+	w.assignIDs()
+	return w
+}
+
+// Bodies can also be appended to World.Bodies directly, so anything
+// without an ID gets one the next time the world is used
+func (w *World) assignIDs() {
+	for _, b := range w.Bodies {
+		if b.id == 0 {
+			w.nextID++
+			b.id = w.nextID
+		}
 	}
 }
 
 // Call this every physics tick
-func (w *World) UpdatePhysics(dt float64) {
+func (w *World) UpdatePhysics(dt Scalar) {
+	w.assignIDs()
+	if w.Deterministic {
+		w.sortBodies()
+	}
+	if w.hook != nil {
+		w.hook.beforeStep(w, dt)
+		defer w.hook.afterStep(w)
+	}
+	for _, b := range w.Bodies {
+		b.previousPosition = b.position
+		b.previousRotation = b.rotation
+	}
 	if w.Paused {
 		return
 	}
 	w.CollisionEvents = w.CollisionEvents[:0]
+	w.sensorBuffer = w.sensorBuffer[:0]
 	for range w.timeSteps {
 		for i, b1 := range w.Bodies {
 
-			// Accelerate due to gravity
-			if b1.inverseMass > 0 {
-				b1.Accelerate(NewVec2(0, -w.gravity))
-			}
-
-			// Resolve forces acting on body
-			b1.Update(dt / float64(w.timeSteps))
+			// Resolve forces (and gravity) acting on body
+			w.integrate(b1, div(dt, fromInt(w.timeSteps)))
 
 			// Check collisions
 			w.collisionBuffer = w.collisionBuffer[:0]
@@ -56,32 +107,69 @@ func (w *World) UpdatePhysics(dt float64) {
 				if b1.inverseMass+b2.inverseMass == 0 {
 					continue
 				}
+				if b1.sensor && b2.sensor {
+					continue
+				}
+				if !w.shouldCollide(b1, b2) {
+					continue
+				}
 				collision, err := Collide(b1, b2)
 				if err != nil {
 					fmt.Fprintln(os.Stderr, err.Error())
 				}
-				if collision != nil {
-					w.collisionBuffer = append(w.collisionBuffer, collision)
-					w.CollisionEvents = append(w.CollisionEvents, collision)
+				if collision == nil {
+					continue
+				}
+				if b1.sensor || b2.sensor {
+					w.addSensorOverlap(b1, b2)
+					continue
 				}
+				w.collisionBuffer = append(w.collisionBuffer, collision)
+				w.CollisionEvents = append(w.CollisionEvents, collision)
+				w.addContact(collision)
 			}
 
 			// Resolve collisions
 			for _, c := range w.collisionBuffer {
+				w.preSolve(c)
 				c.Resolve()
+				w.postSolve(c)
 			}
 		}
 	}
+	w.updateSensorEvents()
+	w.updateContacts()
+}
+
+// Filters are cheap, so check them before the narrow phase
+func (w *World) shouldCollide(a, b *Body) bool {
+	if !a.filter.CollidesWith(b.filter) {
+		return false
+	}
+	if w.ShouldCollide != nil {
+		return w.ShouldCollide(a, b)
+	}
+	return true
 }
 
 func (w *World) AddBody(body *Body) {
 	w.Bodies = append(w.Bodies, body)
+	w.assignIDs()
 }
 
 func (w *World) DeleteBody(bodyIdx int) {
 	w.Bodies = slices.Delete(w.Bodies, bodyIdx, bodyIdx+1)
 }
 
+// In m/s/s, pulling down
+func (w *World) Gravity() Scalar {
+	return w.gravity
+}
+
+func (w *World) Dimensions() Vec2 {
+	return w.dimensions
+}
+
 func (w *World) NumSteps() int {
 	return w.timeSteps
 }